@@ -0,0 +1,324 @@
+// Package astjson implements a versioned JSON encoding of syntax trees.
+//
+// A tree is encoded as an object holding the format version and the root
+// node. Each node is an object with a "type" tag naming its Go type:
+//
+//	{"version":1,"root":{"type":"BinaryExpr","op":"+","opPos":2,
+//	    "left":{"type":"Lit","token":"Number","value":"1","signPos":0,"valuePos":0},
+//	    "right":{"type":"Lit","token":"Constant","value":"-x","signPos":4,"valuePos":5}}}
+package astjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/token"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of the encoding written by Marshal.
+const Version = 1
+
+type document struct {
+	Version int             `json:"version"`
+	Root    json.RawMessage `json:"root"`
+}
+
+type lit struct {
+	Type     string `json:"type"`
+	Token    string `json:"token"`
+	Value    string `json:"value"`
+	SignPos  int    `json:"signPos"`
+	ValuePos int    `json:"valuePos"`
+}
+
+type binaryExpr struct {
+	Type  string          `json:"type"`
+	Op    string          `json:"op"`
+	OpPos int             `json:"opPos"`
+	Left  json.RawMessage `json:"left"`
+	Right json.RawMessage `json:"right"`
+}
+
+type unaryExpr struct {
+	Type    string          `json:"type"`
+	Op      string          `json:"op"`
+	OpPos   int             `json:"opPos"`
+	Postfix bool            `json:"postfix"`
+	X       json.RawMessage `json:"x"`
+}
+
+type badExpr struct {
+	Type string `json:"type"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// tokenTypes holds the literal types which can be encoded.
+var tokenTypes = map[string]token.Type{
+	token.NumberToken.String():   token.NumberToken,
+	token.ConstantToken.String(): token.ConstantToken,
+}
+
+// Marshal returns the JSON encoding of the tree rooted at n.
+func Marshal(n ast.Node) ([]byte, error) {
+	root, err := marshal(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(document{Version: Version, Root: root})
+}
+
+func marshal(n ast.Node) (json.RawMessage, error) {
+	switch n := n.(type) {
+	case ast.Lit:
+		if _, ok := tokenTypes[n.Type.String()]; !ok {
+			return nil, fmt.Errorf("astjson: unsupported literal type %s (%d)", n.Type, n.Type)
+		}
+
+		return json.Marshal(lit{
+			Type:     "Lit",
+			Token:    n.Type.String(),
+			Value:    n.Value,
+			SignPos:  n.SignPos,
+			ValuePos: n.ValuePos,
+		})
+	case ast.BinaryExpr:
+		left, err := marshal(n.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := marshal(n.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(binaryExpr{
+			Type:  "BinaryExpr",
+			Op:    n.Op,
+			OpPos: n.OpPos,
+			Left:  left,
+			Right: right,
+		})
+	case ast.UnaryExpr:
+		x, err := marshal(n.X)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(unaryExpr{
+			Type:    "UnaryExpr",
+			Op:      n.Op,
+			OpPos:   n.OpPos,
+			Postfix: n.Postfix,
+			X:       x,
+		})
+	case ast.BadExpr:
+		return json.Marshal(badExpr{Type: "BadExpr", From: n.From, To: n.To})
+	default:
+		return nil, fmt.Errorf("astjson: unsupported node type %T", n)
+	}
+}
+
+// Unmarshal parses the JSON encoding of a tree and returns its root. The tree
+// is validated, so every literal and operator is one which the parser could
+// have produced with parser.DefaultOptions, within its default limits.
+func Unmarshal(data []byte) (ast.Node, error) {
+	return UnmarshalOptions(data, parser.DefaultOptions())
+}
+
+// UnmarshalOptions parses the JSON encoding of a tree like Unmarshal, but
+// allows the operators in o rather than the default ones. Trees deeper than
+// o.Limits.MaxDepth are rejected, and so are trees nested too deeply for
+// encoding/json.
+func UnmarshalOptions(data []byte, o parser.Options) (ast.Node, error) {
+	var d document
+
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("astjson: %w", err)
+	}
+
+	if d.Version != Version {
+		return nil, fmt.Errorf("astjson: unsupported version %d", d.Version)
+	}
+
+	// The root is decoded separately so that other versions can be rejected
+	// whatever their trees look like.
+	var root *decoded
+
+	if len(d.Root) > 0 {
+		if err := json.Unmarshal(d.Root, &root); err != nil {
+			return nil, fmt.Errorf("astjson: root: %w", err)
+		}
+	}
+
+	return unmarshal(root, &path{field: "root"}, 1, o)
+}
+
+// decoded holds the fields of every type of node, so that a whole tree can be
+// decoded in a single pass rather than once for each level.
+type decoded struct {
+	Type string `json:"type"`
+
+	Token    string `json:"token"`
+	Value    string `json:"value"`
+	SignPos  int    `json:"signPos"`
+	ValuePos int    `json:"valuePos"`
+
+	Op      string   `json:"op"`
+	OpPos   int      `json:"opPos"`
+	Postfix bool     `json:"postfix"`
+	Left    *decoded `json:"left"`
+	Right   *decoded `json:"right"`
+	X       *decoded `json:"x"`
+
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// path is the location of a node in the document. It's only formatted when
+// there's an error, as formatting it at every level of a deep tree would be
+// slow.
+type path struct {
+	parent *path
+	field  string
+}
+
+func (p *path) String() string {
+	var fields []string
+
+	for ; p != nil; p = p.parent {
+		fields = append(fields, p.field)
+	}
+
+	for i, j := 0, len(fields)-1; i < j; i, j = i+1, j-1 {
+		fields[i], fields[j] = fields[j], fields[i]
+	}
+
+	return strings.Join(fields, ".")
+}
+
+// unmarshal converts the node d at p, which is depth levels into the tree and
+// can only use the operators in o.
+func unmarshal(d *decoded, p *path, depth int, o parser.Options) (ast.Node, error) {
+	if d == nil {
+		return nil, fmt.Errorf("astjson: %s: missing node", p)
+	}
+
+	if max := o.Limits.MaxDepth; max > 0 && depth > max {
+		return nil, fmt.Errorf("astjson: %s: exceeded maximum depth of %d", p, max)
+	}
+
+	switch d.Type {
+	case "Lit":
+		n, err := validateLit(lit{Token: d.Token, Value: d.Value, SignPos: d.SignPos, ValuePos: d.ValuePos}, o)
+		if err != nil {
+			return nil, fmt.Errorf("astjson: %s: %w", p, err)
+		}
+
+		return n, nil
+	case "BinaryExpr":
+		if err := validateOp(d.Op, "binary", o.Binary); err != nil {
+			return nil, fmt.Errorf("astjson: %s: %w", p, err)
+		}
+
+		if d.OpPos < 0 {
+			return nil, fmt.Errorf("astjson: %s: invalid operator position %d", p, d.OpPos)
+		}
+
+		left, err := unmarshal(d.Left, &path{p, "left"}, depth+1, o)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := unmarshal(d.Right, &path{p, "right"}, depth+1, o)
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.BinaryExpr{Left: left, Right: right, Op: d.Op, OpPos: d.OpPos}, nil
+	case "UnaryExpr":
+		kind, ops := "prefix", o.Prefix
+		if d.Postfix {
+			kind, ops = "postfix", o.Postfix
+		}
+
+		if err := validateOp(d.Op, kind, ops); err != nil {
+			return nil, fmt.Errorf("astjson: %s: %w", p, err)
+		}
+
+		if d.OpPos < 0 {
+			return nil, fmt.Errorf("astjson: %s: invalid operator position %d", p, d.OpPos)
+		}
+
+		x, err := unmarshal(d.X, &path{p, "x"}, depth+1, o)
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.UnaryExpr{X: x, Op: d.Op, OpPos: d.OpPos, Postfix: d.Postfix}, nil
+	case "BadExpr":
+		if d.From < 0 || d.To < d.From {
+			return nil, fmt.Errorf("astjson: %s: invalid range %d:%d", p, d.From, d.To)
+		}
+
+		return ast.BadExpr{From: d.From, To: d.To}, nil
+	case "":
+		return nil, fmt.Errorf("astjson: %s: missing node type", p)
+	default:
+		return nil, fmt.Errorf("astjson: %s: unknown node type %q", p, d.Type)
+	}
+}
+
+// validateOp returns an error if op isn't one of ops, which are the kind of
+// operators in the parser's options.
+func validateOp(op, kind string, ops map[string]parser.Operator) error {
+	if op == "" {
+		return errors.New("missing operator")
+	}
+
+	if _, ok := ops[op]; !ok {
+		return fmt.Errorf("unknown %s operator %q", kind, op)
+	}
+
+	return nil
+}
+
+// validateLit converts l into a literal, making sure that its value is one
+// which the scanner for o could have produced.
+func validateLit(l lit, o parser.Options) (ast.Lit, error) {
+	t, ok := tokenTypes[l.Token]
+	if !ok {
+		return ast.Lit{}, fmt.Errorf("unknown literal type %q", l.Token)
+	}
+
+	if l.SignPos < 0 || l.ValuePos < 0 {
+		return ast.Lit{}, errors.New("invalid literal position")
+	}
+
+	n := ast.Lit{Type: t, Value: l.Value, SignPos: l.SignPos, ValuePos: l.ValuePos}
+
+	// The value without its sign must scan as exactly one token of the
+	// right type.
+	ts, err := token.NewOperatorScanner(strings.NewReader(n.Unsigned()), o.Operators()).ScanAll()
+	if err != nil {
+		return ast.Lit{}, err
+	}
+
+	if len(ts) != 1 || ts[0].Type != t || ts[0].Value != n.Unsigned() {
+		return ast.Lit{}, fmt.Errorf("invalid %s value %q", l.Token, l.Value)
+	}
+
+	if t == token.NumberToken {
+		if _, err := strconv.ParseFloat(l.Value, 64); err != nil {
+			return ast.Lit{}, fmt.Errorf("invalid %s value %q", l.Token, l.Value)
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,299 @@
+package astjson_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/ast/astjson"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/token"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshal(t *testing.T) {
+	n, err := parser.ParseString("1 + -x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := astjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"version":1,"root":{"type":"BinaryExpr","op":"+","opPos":2,` +
+		`"left":{"type":"Lit","token":"Number","value":"1","signPos":0,"valuePos":0},` +
+		`"right":{"type":"Lit","token":"Constant","value":"-x","signPos":4,"valuePos":5}}}`
+
+	if string(data) != expected {
+		t.Fatalf("expected %s but got %s", expected, data)
+	}
+}
+
+// randomTree returns a random tree with at most depth levels.
+func randomTree(r *rand.Rand, depth int) ast.Node {
+	if depth == 0 || r.Intn(3) == 0 {
+		signs := []string{"", "+", "-"}
+		sign := signs[r.Intn(len(signs))]
+		pos := r.Intn(100)
+
+		if r.Intn(2) == 0 {
+			value := strconv.FormatFloat(r.Float64()*100, 'f', r.Intn(4), 64)
+			return ast.Lit{Type: token.NumberToken, Value: sign + value, SignPos: pos, ValuePos: pos + len(sign)}
+		}
+
+		names := []string{"x", "y", "Pi", "π"}
+		return ast.Lit{Type: token.ConstantToken, Value: sign + names[r.Intn(len(names))], SignPos: pos, ValuePos: pos + len(sign)}
+	}
+
+	ops := []string{"+", "-", "*", "/", "^"}
+
+	return ast.BinaryExpr{
+		Left:  randomTree(r, depth-1),
+		Right: randomTree(r, depth-1),
+		Op:    ops[r.Intn(len(ops))],
+		OpPos: r.Intn(100),
+	}
+}
+
+// TestRoundTrip checks that random trees are unchanged by a round trip, and
+// so evaluate to the same result.
+func TestRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	constants := map[string]float64{"x": 1.5, "y": -2, "Pi": math.Pi, "π": math.Pi}
+
+	for i := 0; i < 1000; i++ {
+		n := randomTree(r, 6)
+
+		data, err := astjson.Marshal(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := astjson.Unmarshal(data)
+		if err != nil {
+			t.Fatalf("%s: %s", data, err)
+		}
+
+		if !reflect.DeepEqual(n, decoded) {
+			t.Fatalf("expected %s but got %s", n, decoded)
+		}
+
+		expected, err := evaluator.Evaluate(n, constants)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := evaluator.Evaluate(decoded, constants)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected != actual && !(math.IsNaN(expected) && math.IsNaN(actual)) {
+			t.Fatalf("%s: expected %v but got %v", n, expected, actual)
+		}
+	}
+}
+
+func TestUnaryAndBadExpr(t *testing.T) {
+	n := ast.BinaryExpr{
+		Left: ast.UnaryExpr{
+			X:       ast.Lit{Type: token.NumberToken, Value: "1"},
+			Op:      "!",
+			OpPos:   1,
+			Postfix: true,
+		},
+		Right: ast.BadExpr{From: 5, To: 7},
+		Op:    "+",
+		OpPos: 3,
+	}
+
+	data, err := astjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := astjson.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actual, ast.Node(n)) {
+		t.Fatalf("expected %#v but got %#v", n, actual)
+	}
+}
+
+func TestUnmarshalOperators(t *testing.T) {
+	one := `{"type":"Lit","token":"Number","value":"1"}`
+
+	cases := []struct {
+		s   string
+		err string
+	}{
+		{
+			`{"version":1,"root":{"type":"BinaryExpr","op":"+","left":{"type":"BinaryExpr","op":"?","left":` + one + `,"right":` + one + `},"right":` + one + `}}`,
+			`astjson: root.left: unknown binary operator "?"`,
+		},
+		{
+			`{"version":1,"root":{"type":"UnaryExpr","op":"-","x":` + one + `}}`,
+			`astjson: root: unknown prefix operator "-"`,
+		},
+		{
+			`{"version":1,"root":{"type":"BinaryExpr","op":"*","left":` + one + `,"right":{"type":"UnaryExpr","op":"~","postfix":true,"x":` + one + `}}}`,
+			`astjson: root.right: unknown postfix operator "~"`,
+		},
+		{
+			`{"version":1,"root":{"type":"UnaryExpr","postfix":true,"x":` + one + `}}`,
+			`astjson: root: missing operator`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := astjson.Unmarshal([]byte(c.s))
+			if err == nil {
+				t.Fatalf("expected an error but got %s", n)
+			}
+
+			if err.Error() != c.err {
+				t.Fatalf("expected error %q but got %q", c.err, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalOptions(t *testing.T) {
+	o := parser.DefaultOptions()
+	o.Binary["?"] = parser.Operator{Precedence: 1}
+	o.Prefix["~"] = parser.Operator{Precedence: 4, Associativity: parser.RightAssociative}
+
+	n, err := o.ParseString("~x ? 2!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := astjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := astjson.Unmarshal(data); err == nil {
+		t.Fatal("expected the default operators to be rejected")
+	}
+
+	actual, err := astjson.UnmarshalOptions(data, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actual, n) {
+		t.Fatalf("expected %s but got %s", n, actual)
+	}
+
+	// Constants can't contain the extra operators, as they'd be split there.
+	data = []byte(`{"version":1,"root":{"type":"Lit","token":"Constant","value":"x~y"}}`)
+
+	if _, err := astjson.Unmarshal(data); err != nil {
+		t.Fatalf("expected x~y to be a constant by default but got %v", err)
+	}
+
+	if n, err := astjson.UnmarshalOptions(data, o); err == nil {
+		t.Fatalf("expected an error but got %s", n)
+	}
+}
+
+// nested returns a document holding a tree of depth levels, made up of
+// factorials of 1.
+func nested(depth int) string {
+	return `{"version":1,"root":` +
+		strings.Repeat(`{"type":"UnaryExpr","op":"!","opPos":1,"postfix":true,"x":`, depth-1) +
+		`{"type":"Lit","token":"Number","value":"1"}` +
+		strings.Repeat("}", depth-1) + "}"
+}
+
+func TestUnmarshalDepth(t *testing.T) {
+	small := parser.DefaultOptions()
+	small.Limits.MaxDepth = 3
+
+	unlimited := parser.DefaultOptions()
+	unlimited.Limits.MaxDepth = 0
+
+	cases := []struct {
+		s   string
+		o   parser.Options
+		err string
+	}{
+		{nested(1000), parser.DefaultOptions(), ""},
+		{nested(1001), parser.DefaultOptions(), "exceeded maximum depth of 1000"},
+		{nested(9000), parser.DefaultOptions(), "exceeded maximum depth of 1000"},
+		{nested(3), small, ""},
+		{nested(4), small, "astjson: root.x.x.x: exceeded maximum depth of 3"},
+		{nested(9000), unlimited, ""},
+		{nested(1000000), unlimited, "exceeded max depth"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			start := time.Now()
+			n, err := astjson.UnmarshalOptions([]byte(c.s), c.o)
+
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("expected unmarshalling to be quick but it took %s", elapsed)
+			}
+
+			if c.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected an error containing %q but got %v (%v)", c.err, err, n)
+			}
+		})
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	cases := []string{
+		`nope`,
+		`{"version":2,"root":{"type":"Lit","token":"Number","value":"1"}}`,
+		`{"version":1}`,
+		`{"version":1,"root":null}`,
+		`{"version":1,"root":{}}`,
+		`{"version":1,"root":{"type":"Call"}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Operator","value":"+"}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Number","value":""}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Number","value":"1.2.3"}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Number","value":"--1"}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Number","value":"x"}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Constant","value":"1"}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Constant","value":"a b"}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Constant","value":"a+b"}}`,
+		`{"version":1,"root":{"type":"Lit","token":"Number","value":"1","valuePos":-1}}`,
+		`{"version":1,"root":{"type":"BinaryExpr","op":"+","left":{"type":"Lit","token":"Number","value":"1"}}}`,
+		`{"version":1,"root":{"type":"BinaryExpr","right":{"type":"Lit","token":"Number","value":"1"},"left":{"type":"Lit","token":"Number","value":"1"}}}`,
+		`{"version":1,"root":{"type":"UnaryExpr","op":"!","postfix":true}}`,
+		`{"version":1,"root":{"type":"UnaryExpr","x":{"type":"Lit","token":"Number","value":"1"}}}`,
+		`{"version":1,"root":{"type":"BinaryExpr","op":"!","left":{"type":"Lit","token":"Number","value":"1"},"right":{"type":"Lit","token":"Number","value":"1"}}}`,
+		`{"version":1,"root":{"type":"UnaryExpr","op":"+","postfix":true,"x":{"type":"Lit","token":"Number","value":"1"}}}`,
+		`{"version":1,"root":{"type":"UnaryExpr","op":"!","x":{"type":"Lit","token":"Number","value":"1"}}}`,
+		`{"version":1,"root":{"type":"BadExpr","from":3,"to":2}}`,
+		`{"version":1,"root":{"type":"BadExpr","from":-1,"to":2}}`,
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			if n, err := astjson.Unmarshal([]byte(c)); err == nil {
+				t.Fatalf("expected an error but got %s", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package astutil
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+)
+
+// A Ref is a constant referenced by a tree.
+type Ref struct {
+	// Name is the name of the constant, without any sign.
+	Name string
+
+	// Positions holds the position of each reference to the constant, in
+	// source order.
+	Positions []int
+}
+
+// Refs returns every constant referenced by the tree rooted at n, in order of
+// their first reference.
+func Refs(n ast.Node) []Ref {
+	var refs []Ref
+	indexes := make(map[string]int)
+
+	ast.Inspect(n, func(n ast.Node) bool {
+		l, ok := n.(ast.Lit)
+		if !ok || l.Type != token.ConstantToken {
+			return true
+		}
+
+		name := l.Unsigned()
+
+		i, ok := indexes[name]
+		if !ok {
+			i = len(refs)
+			indexes[name] = i
+			refs = append(refs, Ref{Name: name})
+		}
+
+		refs[i].Positions = append(refs[i].Positions, l.ValuePos)
+
+		return true
+	})
+
+	return refs
+}
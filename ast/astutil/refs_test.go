@@ -0,0 +1,47 @@
+package astutil_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast/astutil"
+	"github.com/jackwilsdon/go-calc/parser"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestRefs(t *testing.T) {
+	cases := []struct {
+		s    string
+		refs []astutil.Ref
+	}{
+		{"1 + 2", nil},
+		{"x", []astutil.Ref{{"x", []int{0}}}},
+		{
+			"rate * -x + x / Pi - -rate",
+			[]astutil.Ref{
+				{"rate", []int{0, 22}},
+				{"x", []int{8, 12}},
+				{"Pi", []int{16}},
+			},
+		},
+		{
+			"(y ^ 2) ^ π + y",
+			[]astutil.Ref{
+				{"y", []int{1, 14}},
+				{"π", []int{10}},
+			},
+		},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if refs := astutil.Refs(n); !reflect.DeepEqual(refs, c.refs) {
+				t.Fatalf("expected %v but got %v", c.refs, refs)
+			}
+		})
+	}
+}
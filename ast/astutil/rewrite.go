@@ -0,0 +1,134 @@
+// Package astutil contains helpers for working with the calculator's syntax
+// trees.
+package astutil
+
+import (
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+)
+
+// An ApplyFunc is invoked by Apply for each node n before and/or after the
+// node's children, using a Cursor describing the current node and providing
+// operations on it. It is invoked for a nil root, but nil children are
+// skipped.
+//
+// The return value of ApplyFunc controls the syntax tree traversal.
+// See Apply for details.
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses a syntax tree recursively, starting with root, and calling
+// pre and post for each node as described below. Apply returns the syntax tree,
+// possibly modified.
+//
+// If pre is not nil, it is called for each node before the node's children
+// are traversed (pre-order). If pre returns false, no children are traversed,
+// and post is not called for that node.
+//
+// If post is not nil, and a prior call of pre didn't return false, post is
+// called for each node after its children are traversed (post-order). If post
+// returns false, traversal is terminated and Apply returns immediately.
+//
+// Only fields that refer to AST nodes are considered children.
+//
+// Nodes are values, so replacing a node never modifies the tree that was
+// passed in. Instead, every parent of a replaced node is copied with the new
+// child in place, and the new root is returned.
+func Apply(root ast.Node, pre, post ApplyFunc) (result ast.Node) {
+	a := application{pre: pre, post: post}
+	return a.apply(nil, "", root)
+}
+
+// A Cursor describes a node encountered during Apply. Information about the
+// node and its parent is available from the Node, Parent and Name methods.
+type Cursor struct {
+	parent   ast.Node
+	name     string
+	node     ast.Node
+	replaced bool
+}
+
+// Node returns the current Node.
+func (c *Cursor) Node() ast.Node {
+	return c.node
+}
+
+// Parent returns the parent of the current Node. Children which have already
+// been traversed are reflected in the returned parent.
+func (c *Cursor) Parent() ast.Node {
+	return c.parent
+}
+
+// Name returns the name of the parent Node field that contains the current
+// Node, such as "Left" or "X", or an empty string for the root.
+func (c *Cursor) Name() string {
+	return c.name
+}
+
+// Replace replaces the current Node with n. The replacement node is not
+// walked by Apply, although post is still called for it.
+func (c *Cursor) Replace(n ast.Node) {
+	c.node = n
+	c.replaced = true
+}
+
+type application struct {
+	pre, post ApplyFunc
+	cursor    Cursor
+	stopped   bool
+}
+
+// apply walks n and returns the node which should take its place.
+func (a *application) apply(parent ast.Node, name string, n ast.Node) ast.Node {
+	// Once post has asked us to stop we leave everything else untouched.
+	if a.stopped {
+		return n
+	}
+
+	// Save the cursor so that it can be restored for our parent.
+	saved := a.cursor
+	defer func() { a.cursor = saved }()
+
+	a.cursor = Cursor{parent: parent, name: name, node: n}
+
+	if a.pre != nil && !a.pre(&a.cursor) {
+		return a.cursor.node
+	}
+
+	// Rebuild the node from its traversed children, unless pre replaced it.
+	if !a.cursor.replaced {
+		a.cursor.node = a.children(a.cursor.node)
+	}
+
+	if a.post != nil && !a.stopped && !a.post(&a.cursor) {
+		a.stopped = true
+	}
+
+	return a.cursor.node
+}
+
+// children applies to each child of n and returns a copy of n with the
+// results in place.
+func (a *application) children(n ast.Node) ast.Node {
+	switch n := n.(type) {
+	case nil, ast.Lit, ast.BadExpr:
+		return n
+	case ast.BinaryExpr:
+		if n.Left != nil {
+			n.Left = a.apply(n, "Left", n.Left)
+		}
+
+		if n.Right != nil {
+			n.Right = a.apply(n, "Right", n.Right)
+		}
+
+		return n
+	case ast.UnaryExpr:
+		if n.X != nil {
+			n.X = a.apply(n, "X", n.X)
+		}
+
+		return n
+	default:
+		panic(fmt.Sprintf("astutil.Apply: unexpected node type %T", n))
+	}
+}
@@ -0,0 +1,173 @@
+package astutil_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/ast/astutil"
+	"github.com/jackwilsdon/go-calc/parser"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		s         string
+		pre, post astutil.ApplyFunc
+		expected  string
+	}{
+		{
+			"1 + 2",
+			nil,
+			nil,
+			"(1 + 2)",
+		},
+		{
+			// Replace a constant everywhere it appears.
+			"x * (x + 1)",
+			func(c *astutil.Cursor) bool {
+				if l, ok := c.Node().(ast.Lit); ok && l.Value == "x" {
+					c.Replace(ast.Lit{Type: l.Type, Value: "y"})
+				}
+
+				return true
+			},
+			nil,
+			"(y * (y + 1))",
+		},
+		{
+			// Swap the operands of every addition on the way back up.
+			"(1 + 2) * (3 + 4 + 5)",
+			nil,
+			func(c *astutil.Cursor) bool {
+				if b, ok := c.Node().(ast.BinaryExpr); ok && b.Op == "+" {
+					c.Replace(ast.BinaryExpr{Left: b.Right, Right: b.Left, Op: b.Op})
+				}
+
+				return true
+			},
+			"((2 + 1) * (5 + (4 + 3)))",
+		},
+		{
+			// Replacements made in pre are not walked.
+			"1 + 2",
+			func(c *astutil.Cursor) bool {
+				if l, ok := c.Node().(ast.Lit); ok && l.Value == "1" {
+					c.Replace(ast.BinaryExpr{Left: l, Right: l, Op: "*"})
+				}
+
+				return true
+			},
+			nil,
+			"((1 * 1) + 2)",
+		},
+		{
+			// Returning false from pre skips the children and post.
+			"(1 + 2) * (3 + 4)",
+			func(c *astutil.Cursor) bool {
+				return c.Name() != "Left"
+			},
+			func(c *astutil.Cursor) bool {
+				if l, ok := c.Node().(ast.Lit); ok {
+					c.Replace(ast.Lit{Type: l.Type, Value: l.Value + "0"})
+				}
+
+				return true
+			},
+			"((1 + 2) * (3 + 40))",
+		},
+		{
+			// Returning false from post stops the traversal.
+			"1 + 2 + 3",
+			nil,
+			func(c *astutil.Cursor) bool {
+				if l, ok := c.Node().(ast.Lit); ok {
+					c.Replace(ast.Lit{Type: l.Type, Value: l.Value + "0"})
+					return l.Value != "2"
+				}
+
+				return true
+			},
+			"((10 + 20) + 3)",
+		},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			original := n.String()
+			result := astutil.Apply(n, c.pre, c.post)
+
+			if result.String() != c.expected {
+				t.Errorf("expected %s but got %s", c.expected, result)
+			}
+
+			if n.String() != original {
+				t.Errorf("expected original tree to be %s but got %s", original, n)
+			}
+		})
+	}
+}
+
+func TestApplyCursor(t *testing.T) {
+	n, err := parser.ParseString("1 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parents, names []string
+
+	astutil.Apply(n, func(c *astutil.Cursor) bool {
+		if c.Parent() == nil {
+			parents = append(parents, "nil")
+		} else {
+			parents = append(parents, c.Parent().String())
+		}
+
+		names = append(names, c.Name())
+
+		// Make sure the parent of the right hand side reflects this change.
+		if l, ok := c.Node().(ast.Lit); ok && l.Value == "1" {
+			c.Replace(ast.Lit{Type: l.Type, Value: "3"})
+		}
+
+		return true
+	}, nil)
+
+	if expected := "nil,(1 + 2),(3 + 2)"; strings.Join(parents, ",") != expected {
+		t.Errorf("expected parents %s but got %s", expected, strings.Join(parents, ","))
+	}
+
+	if expected := ",Left,Right"; strings.Join(names, ",") != expected {
+		t.Errorf("expected names %s but got %s", expected, strings.Join(names, ","))
+	}
+}
+
+func TestApplyNil(t *testing.T) {
+	cases := []struct {
+		n     ast.Node
+		calls int
+	}{
+		{nil, 1},
+		{ast.BinaryExpr{Left: ast.Lit{Value: "1"}, Op: "+"}, 2},
+		{ast.UnaryExpr{Op: "-"}, 1},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			var calls int
+
+			astutil.Apply(c.n, func(*astutil.Cursor) bool {
+				calls++
+				return true
+			}, nil)
+
+			if calls != c.calls {
+				t.Fatalf("expected %d calls but got %d", c.calls, calls)
+			}
+		})
+	}
+}
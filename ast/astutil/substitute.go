@@ -0,0 +1,31 @@
+package astutil
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+)
+
+// Substitute returns a copy of n with each reference to a constant in
+// bindings replaced by its bound node. Negated references such as -x are
+// replaced by the negation of the bound node.
+func Substitute(n ast.Node, bindings map[string]ast.Node) ast.Node {
+	return Apply(n, func(c *Cursor) bool {
+		l, ok := c.Node().(ast.Lit)
+		if !ok || l.Type != token.ConstantToken {
+			return true
+		}
+
+		r, ok := bindings[l.Unsigned()]
+		if !ok {
+			return true
+		}
+
+		if l.Negative() {
+			r = ast.Negate(r, r.Pos())
+		}
+
+		c.Replace(r)
+
+		return true
+	}, nil)
+}
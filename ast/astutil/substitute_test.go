@@ -0,0 +1,53 @@
+package astutil_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/ast/astutil"
+	"github.com/jackwilsdon/go-calc/parser"
+	"strconv"
+	"testing"
+)
+
+func TestSubstitute(t *testing.T) {
+	bindings := map[string]string{
+		"x": "2",
+		"y": "-z",
+		"z": "a + b",
+	}
+
+	cases := []struct {
+		s, expected string
+	}{
+		{"1", "1"},
+		{"w", "w"},
+		{"x * w", "(2 * w)"},
+		{"-x + +x", "(-2 + 2)"},
+		{"y - -y", "(-z - z)"},
+		{"z * -z", "((a + b) * (-1 * (a + b)))"},
+		{"x ^ y ^ z", "(2 ^ (-z ^ (a + b)))"},
+	}
+
+	nodes := make(map[string]ast.Node)
+
+	for name, s := range bindings {
+		n, err := parser.ParseString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		nodes[name] = n
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := astutil.Substitute(n, nodes).String(); s != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dot returns a Graphviz DOT graph of the tree rooted at n. Each node is
+// labelled with its operator or value and its position in the source.
+func Dot(n Node) string {
+	b := strings.Builder{}
+	b.WriteString("digraph ast {\n")
+	b.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	var id int
+	var write func(n Node) string
+
+	// write writes n and its children and returns the identifier of n.
+	write = func(n Node) string {
+		name := "n" + strconv.Itoa(id)
+		id++
+
+		var label string
+		var children []Node
+		var edges []string
+
+		switch n := n.(type) {
+		case Lit:
+			label = fmt.Sprintf("%s\n%s %d:%d", n.Value, n.Type, n.Pos(), n.End())
+		case BinaryExpr:
+			label = fmt.Sprintf("%s\nOperator %d", n.Op, n.OpPos)
+			children = []Node{n.Left, n.Right}
+			edges = []string{"Left", "Right"}
+		case UnaryExpr:
+			kind := "Prefix"
+			if n.Postfix {
+				kind = "Postfix"
+			}
+
+			label = fmt.Sprintf("%s\n%s %d", n.Op, kind, n.OpPos)
+			children = []Node{n.X}
+			edges = []string{"X"}
+		case BadExpr:
+			label = fmt.Sprintf("%s\n%d:%d", n, n.From, n.To)
+		default:
+			panic(fmt.Sprintf("ast.Dot: unexpected node type %T", n))
+		}
+
+		b.WriteString("\t" + name + " [label=" + strconv.Quote(label) + "];\n")
+
+		for i, c := range children {
+			child := write(c)
+			b.WriteString("\t" + name + " -> " + child + " [label=" + strconv.Quote(edges[i]) + "];\n")
+		}
+
+		return name
+	}
+
+	write(n)
+	b.WriteString("}\n")
+
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package ast_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"testing"
+)
+
+func TestDot(t *testing.T) {
+	n, err := parser.ParseString("1 + -π * 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `digraph ast {
+	node [shape=box, fontname="monospace"];
+	n0 [label="+\nOperator 2"];
+	n1 [label="1\nNumber 0:1"];
+	n0 -> n1 [label="Left"];
+	n2 [label="*\nOperator 7"];
+	n3 [label="-π\nConstant 4:6"];
+	n2 -> n3 [label="Left"];
+	n4 [label="2\nNumber 9:10"];
+	n2 -> n4 [label="Right"];
+	n0 -> n2 [label="Right"];
+}
+`
+
+	if s := ast.Dot(n); s != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, s)
+	}
+}
@@ -0,0 +1,137 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/jackwilsdon/go-calc/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// latexConstants holds the LaTeX for constants with a well known symbol.
+var latexConstants = map[string]string{
+	"Pi":  `\pi`,
+	"π":   `\pi`,
+	"Inf": `\infty`,
+	"∞":   `\infty`,
+}
+
+// latexEscaper escapes characters with special meaning in LaTeX.
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`_`, `\_`,
+	`#`, `\#`,
+	`$`, `\$`,
+	`%`, `\%`,
+	`&`, `\&`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+// LaTeX returns a LaTeX rendering of the tree rooted at n, with only the
+// parentheses needed to keep its grouping. It uses DefaultStyle.
+func LaTeX(n Node) string {
+	return DefaultStyle().LaTeX(n)
+}
+
+// LaTeX returns a LaTeX rendering of the tree rooted at n in style s.
+func (s Style) LaTeX(n Node) string {
+	switch n := n.(type) {
+	case Lit:
+		return latexLit(n)
+	case BinaryExpr:
+		return s.latexBinary(n)
+	case UnaryExpr:
+		return s.latexUnary(n)
+	case BadExpr:
+		return `\text{?}`
+	default:
+		panic(fmt.Sprintf("ast.LaTeX: unexpected node type %T", n))
+	}
+}
+
+func latexLit(l Lit) string {
+	var sign string
+
+	if l.signed() {
+		sign = l.Value[:1]
+	}
+
+	name := l.Unsigned()
+
+	if l.Type == token.NumberToken {
+		return sign + name
+	}
+
+	if s, ok := latexConstants[name]; ok {
+		return sign + s
+	}
+
+	// Single letters are written as variables, and anything longer upright.
+	if utf8.RuneCountInString(name) == 1 {
+		return sign + latexEscaper.Replace(name)
+	}
+
+	return sign + `\mathrm{` + latexEscaper.Replace(name) + `}`
+}
+
+// latexOperand renders the operand n of the binary operator op.
+func (s Style) latexOperand(op string, n Node, right bool) string {
+	// Fractions and powers are already grouped by their layout.
+	if s.laidOut(n) {
+		return s.LaTeX(n)
+	}
+
+	if s.needsParens(op, n, right) {
+		return `\left(` + s.LaTeX(n) + `\right)`
+	}
+
+	return s.LaTeX(n)
+}
+
+func (s Style) latexBinary(b BinaryExpr) string {
+	switch {
+	case s.Fractions && b.Op == "/":
+		// Fractions group their operands themselves.
+		return `\frac{` + s.LaTeX(b.Left) + `}{` + s.LaTeX(b.Right) + `}`
+	case s.Powers && b.Op == "^":
+		// Exponents are grouped by their braces, but anything more than a
+		// single value in the base needs parentheses.
+		base := s.LaTeX(b.Left)
+
+		if l, ok := b.Left.(Lit); !ok || l.signed() {
+			base = `\left(` + base + `\right)`
+		}
+
+		return base + `^{` + s.LaTeX(b.Right) + `}`
+	}
+
+	op := b.Op
+
+	switch {
+	case s.Products && op == "*":
+		op = `\cdot`
+	case op == "+", op == "-":
+	default:
+		op = `\mathbin{` + latexEscaper.Replace(op) + `}`
+	}
+
+	return s.latexOperand(b.Op, b.Left, false) + " " + op + " " + s.latexOperand(b.Op, b.Right, true)
+}
+
+func (s Style) latexUnary(u UnaryExpr) string {
+	x := s.LaTeX(u.X)
+
+	if unaryNeedsParens(u.X) {
+		x = `\left(` + x + `\right)`
+	}
+
+	op := latexEscaper.Replace(u.Op)
+
+	if u.Postfix {
+		return x + op
+	}
+
+	return op + x
+}
@@ -0,0 +1,123 @@
+package ast_test
+
+import (
+	"bufio"
+	"flag"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenOptions adds some prefix operators to the defaults, so that the golden
+// files cover them too.
+func goldenOptions() parser.Options {
+	o := parser.DefaultOptions()
+	o.Prefix["-"] = parser.Operator{Precedence: 2, Associativity: parser.RightAssociative}
+	o.Prefix["~"] = parser.Operator{Precedence: 4, Associativity: parser.RightAssociative}
+	return o
+}
+
+// testGolden renders each expression in testdata/exprs.txt with render and
+// compares the results against the named golden file, one line per
+// expression.
+func testGolden(t *testing.T, name string, render func(ast.Node) string) {
+	f, err := os.Open(filepath.Join("testdata", "exprs.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	b := strings.Builder{}
+	s := bufio.NewScanner(f)
+	o := goldenOptions()
+
+	for s.Scan() {
+		n, err := o.ParseString(s.Text())
+		if err != nil {
+			t.Fatalf("%s: %s", s.Text(), err)
+		}
+
+		b.WriteString(render(n) + "\n")
+	}
+
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", name)
+	actual := b.String()
+
+	if *update {
+		if err := os.WriteFile(golden, []byte(actual), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLines := strings.Split(string(expected), "\n")
+	actualLines := strings.Split(actual, "\n")
+
+	if len(expectedLines) != len(actualLines) {
+		t.Fatalf("expected %d lines but got %d", len(expectedLines), len(actualLines))
+	}
+
+	for i := range expectedLines {
+		if expectedLines[i] != actualLines[i] {
+			t.Errorf("line %d: expected %s but got %s", i+1, expectedLines[i], actualLines[i])
+		}
+	}
+}
+
+func TestLaTeX(t *testing.T) {
+	testGolden(t, "latex.golden", ast.LaTeX)
+}
+
+// xorStyle returns a parser and style where "^" is a left associative operator
+// with the same precedence as "+", such as bitwise XOR.
+func xorStyle() (parser.Options, ast.Style) {
+	o := parser.DefaultOptions()
+	o.Binary["^"] = parser.Operator{Precedence: 1, Associativity: parser.LeftAssociative}
+
+	s := ast.DefaultStyle()
+	s.Binary["^"] = ast.Operator{Precedence: 1}
+	s.Powers = false
+
+	return o, s
+}
+
+func TestStyleLaTeX(t *testing.T) {
+	o, style := xorStyle()
+
+	cases := []struct {
+		s, expected string
+	}{
+		{"2 ^ 3", `2 \mathbin{\textasciicircum{}} 3`},
+		{"x ^ (y ^ z)", `x \mathbin{\textasciicircum{}} \left(y \mathbin{\textasciicircum{}} z\right)`},
+		{"x ^ y + 1", `x \mathbin{\textasciicircum{}} y + 1`},
+		{"(x ^ y) * 2", `\left(x \mathbin{\textasciicircum{}} y\right) \cdot 2`},
+		{"x / 2 ^ 1", `\frac{x}{2} \mathbin{\textasciicircum{}} 1`},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := o.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := style.LaTeX(n); s != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/jackwilsdon/go-calc/token"
+	"html"
+)
+
+// mathMLConstants holds the MathML identifiers for constants with a well
+// known symbol.
+var mathMLConstants = map[string]string{
+	"Pi":  "π",
+	"Inf": "∞",
+}
+
+// MathML returns a presentation MathML rendering of the tree rooted at n,
+// with only the parentheses needed to keep its grouping. It uses
+// DefaultStyle.
+func MathML(n Node) string {
+	return DefaultStyle().MathML(n)
+}
+
+// MathML returns a presentation MathML rendering of the tree rooted at n in
+// style s.
+func (s Style) MathML(n Node) string {
+	return `<math xmlns="http://www.w3.org/1998/Math/MathML">` + s.mathML(n) + `</math>`
+}
+
+// mathML returns the MathML for n as a single element.
+func (s Style) mathML(n Node) string {
+	switch n := n.(type) {
+	case Lit:
+		return mathMLLit(n)
+	case BinaryExpr:
+		return s.mathMLBinary(n)
+	case UnaryExpr:
+		return s.mathMLUnary(n)
+	case BadExpr:
+		return "<merror><mtext>?</mtext></merror>"
+	default:
+		panic(fmt.Sprintf("ast.MathML: unexpected node type %T", n))
+	}
+}
+
+func mathMLLit(l Lit) string {
+	var value string
+
+	if l.Type == token.NumberToken {
+		value = "<mn>" + html.EscapeString(l.Unsigned()) + "</mn>"
+	} else if s, ok := mathMLConstants[l.Unsigned()]; ok {
+		value = "<mi>" + s + "</mi>"
+	} else {
+		value = "<mi>" + html.EscapeString(l.Unsigned()) + "</mi>"
+	}
+
+	if !l.signed() {
+		return value
+	}
+
+	return "<mrow><mo>" + l.Value[:1] + "</mo>" + value + "</mrow>"
+}
+
+// mathMLParens wraps s in parentheses.
+func mathMLParens(s string) string {
+	return "<mrow><mo>(</mo>" + s + "<mo>)</mo></mrow>"
+}
+
+// mathMLOperand renders the operand n of the binary operator op.
+func (s Style) mathMLOperand(op string, n Node, right bool) string {
+	// Fractions and powers are already grouped by their layout.
+	if s.laidOut(n) {
+		return s.mathML(n)
+	}
+
+	if s.needsParens(op, n, right) {
+		return mathMLParens(s.mathML(n))
+	}
+
+	return s.mathML(n)
+}
+
+func (s Style) mathMLBinary(b BinaryExpr) string {
+	switch {
+	case s.Fractions && b.Op == "/":
+		// Fractions group their operands themselves.
+		return "<mfrac>" + s.mathML(b.Left) + s.mathML(b.Right) + "</mfrac>"
+	case s.Powers && b.Op == "^":
+		// Exponents are grouped by their layout, but anything more than a
+		// single value in the base needs parentheses.
+		base := s.mathML(b.Left)
+
+		if l, ok := b.Left.(Lit); !ok || l.signed() {
+			base = mathMLParens(base)
+		}
+
+		return "<msup>" + base + s.mathML(b.Right) + "</msup>"
+	}
+
+	op := b.Op
+
+	if s.Products && op == "*" {
+		op = "⋅"
+	}
+
+	return "<mrow>" +
+		s.mathMLOperand(b.Op, b.Left, false) +
+		"<mo>" + html.EscapeString(op) + "</mo>" +
+		s.mathMLOperand(b.Op, b.Right, true) +
+		"</mrow>"
+}
+
+func (s Style) mathMLUnary(u UnaryExpr) string {
+	x := s.mathML(u.X)
+
+	if unaryNeedsParens(u.X) {
+		x = mathMLParens(x)
+	}
+
+	op := "<mo>" + html.EscapeString(u.Op) + "</mo>"
+
+	if u.Postfix {
+		return "<mrow>" + x + op + "</mrow>"
+	}
+
+	return "<mrow>" + op + x + "</mrow>"
+}
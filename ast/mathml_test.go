@@ -0,0 +1,37 @@
+package ast_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"strconv"
+	"testing"
+)
+
+func TestMathML(t *testing.T) {
+	testGolden(t, "mathml.golden", ast.MathML)
+}
+
+func TestStyleMathML(t *testing.T) {
+	o, style := xorStyle()
+
+	cases := []struct {
+		s, expected string
+	}{
+		{"2 ^ 3", "<mrow><mn>2</mn><mo>^</mo><mn>3</mn></mrow>"},
+		{"(x ^ y) * 2", "<mrow><mrow><mo>(</mo><mrow><mi>x</mi><mo>^</mo><mi>y</mi></mrow><mo>)</mo></mrow><mo>⋅</mo><mn>2</mn></mrow>"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := o.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := `<math xmlns="http://www.w3.org/1998/Math/MathML">` + c.expected + `</math>`
+
+			if s := style.MathML(n); s != expected {
+				t.Fatalf("expected %s but got %s", expected, s)
+			}
+		})
+	}
+}
@@ -3,27 +3,205 @@ package ast
 import (
 	"fmt"
 	"github.com/jackwilsdon/go-calc/token"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
 )
 
-type Node fmt.Stringer
+// Node is implemented by every node in the syntax tree. Positions are rune
+// offsets into the source, matching token.Token.Position.
+type Node interface {
+	fmt.Stringer
 
+	// Pos returns the position of the first character of the node.
+	Pos() int
+
+	// End returns the position of the first character after the node.
+	End() int
+
+	// node prevents types outside of this package from implementing Node.
+	node()
+}
+
+// Lit is a number or constant, with any signs which preceded it collapsed
+// into the front of Value.
 type Lit struct {
 	Type  token.Type
 	Value string
+
+	// SignPos is the position of the first sign, and is only meaningful if
+	// Value is signed.
+	SignPos int
+
+	// ValuePos is the position of the number or constant itself.
+	ValuePos int
+}
+
+// NewNumber returns a number literal for v, or false if v can't be written as
+// a literal (NaN and the infinities).
+func NewNumber(v float64) (Lit, bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return Lit{}, false
+	}
+
+	// The scanner doesn't understand exponents, so always write the number
+	// out in full.
+	return Lit{Type: token.NumberToken, Value: strconv.FormatFloat(v, 'f', -1, 64)}, true
+}
+
+// NewNumberAt returns a number literal for v like NewNumber, with its sign
+// and value both positioned at pos.
+func NewNumberAt(v float64, pos int) (Lit, bool) {
+	l, ok := NewNumber(v)
+	l.SignPos = pos
+	l.ValuePos = pos
+	return l, ok
+}
+
+// NewInt returns a number literal for v positioned at pos. Unlike NewNumber,
+// every digit of v is kept.
+func NewInt(v *big.Int, pos int) Lit {
+	return Lit{Type: token.NumberToken, Value: v.String(), SignPos: pos, ValuePos: pos}
+}
+
+// Negate returns the negation of n. Literals have their sign flipped, and
+// anything else is multiplied by -1 with the operator at pos, as that's exact
+// unlike subtracting from zero.
+func Negate(n Node, pos int) Node {
+	if l, ok := n.(Lit); ok {
+		if l.Negative() {
+			l.Value = l.Unsigned()
+		} else {
+			l.Value = "-" + l.Unsigned()
+			l.SignPos = l.ValuePos
+		}
+
+		return l
+	}
+
+	minusOne, _ := NewNumberAt(-1, n.Pos())
+	return BinaryExpr{Left: minusOne, Right: n, Op: "*", OpPos: pos}
 }
 
 func (l Lit) String() string {
 	return l.Value
 }
 
+// signed returns whether the literal has a sign at the front of its value.
+func (l Lit) signed() bool {
+	return len(l.Value) > 0 && (l.Value[0] == '+' || l.Value[0] == '-')
+}
+
+// Negative returns whether the literal has a negative sign.
+func (l Lit) Negative() bool {
+	return len(l.Value) > 0 && l.Value[0] == '-'
+}
+
+// Unsigned returns the value of the literal without its sign.
+func (l Lit) Unsigned() string {
+	if l.signed() {
+		return l.Value[1:]
+	}
+
+	return l.Value
+}
+
+func (l Lit) Pos() int {
+	if l.signed() {
+		return l.SignPos
+	}
+
+	return l.ValuePos
+}
+
+func (l Lit) End() int {
+	return l.ValuePos + utf8.RuneCountInString(l.Unsigned())
+}
+
 type BinaryExpr struct {
 	Left, Right Node
 	Op          string
+
+	// OpPos is the position of the operator.
+	OpPos int
 }
 
 func (b BinaryExpr) String() string {
 	return "(" + b.Left.String() + " " + b.Op + " " + b.Right.String() + ")"
 }
 
+func (b BinaryExpr) Pos() int {
+	return b.Left.Pos()
+}
+
+func (b BinaryExpr) End() int {
+	return b.Right.End()
+}
+
+// UnaryExpr is a prefix or postfix operator applied to a single operand, such
+// as "~x" or "5!". Signs directly before a number or constant are part of
+// the Lit instead.
+type UnaryExpr struct {
+	X  Node
+	Op string
+
+	// OpPos is the position of the operator.
+	OpPos int
+
+	// Postfix is set if the operator is written after its operand.
+	Postfix bool
+}
+
+func (u UnaryExpr) String() string {
+	if u.Postfix {
+		return "(" + u.X.String() + u.Op + ")"
+	}
+
+	return "(" + u.Op + u.X.String() + ")"
+}
+
+func (u UnaryExpr) Pos() int {
+	if u.Postfix {
+		return u.X.Pos()
+	}
+
+	return u.OpPos
+}
+
+func (u UnaryExpr) End() int {
+	if u.Postfix {
+		return u.OpPos + utf8.RuneCountInString(u.Op)
+	}
+
+	return u.X.End()
+}
+
+// BadExpr is a placeholder for an expression containing syntax errors, for
+// which no correct node can be created. It is only produced by the parser when
+// recovering from errors.
+type BadExpr struct {
+	From, To int
+}
+
+func (BadExpr) String() string {
+	return "BadExpr"
+}
+
+func (b BadExpr) Pos() int {
+	return b.From
+}
+
+func (b BadExpr) End() int {
+	return b.To
+}
+
+func (Lit) node()        {}
+func (BinaryExpr) node() {}
+func (UnaryExpr) node()  {}
+func (BadExpr) node()    {}
+
 var _ Node = Lit{}
 var _ Node = BinaryExpr{}
+var _ Node = UnaryExpr{}
+var _ Node = BadExpr{}
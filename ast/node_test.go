@@ -0,0 +1,74 @@
+package ast_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+func TestNewNumberAt(t *testing.T) {
+	cases := []struct {
+		v        float64
+		expected string
+		ok       bool
+	}{
+		{0, "0", true},
+		{-2.5, "-2.5", true},
+		{1e21, "1000000000000000000000", true},
+		{math.Inf(1), "", false},
+		{math.NaN(), "", false},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			l, ok := ast.NewNumberAt(c.v, 7)
+
+			if ok != c.ok {
+				t.Fatalf("expected ok to be %v", c.ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			if l.Value != c.expected || l.Pos() != 7 {
+				t.Fatalf("expected %s at 7 but got %s at %d", c.expected, l, l.Pos())
+			}
+		})
+	}
+}
+
+func TestNewInt(t *testing.T) {
+	v, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+
+	if l := ast.NewInt(v, 3); l.Value != v.String() || l.Pos() != 3 {
+		t.Fatalf("expected %s at 3 but got %s at %d", v, l, l.Pos())
+	}
+}
+
+func TestNegate(t *testing.T) {
+	cases := []struct {
+		s, expected string
+	}{
+		{"2", "-2"},
+		{"-x", "x"},
+		{"x + 1", "(-1 * (x + 1))"},
+		{"x!", "(-1 * (x!))"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := ast.Negate(n, 0).String(); s != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package ast
+
+// Operator describes how a binary operator groups when it's printed.
+type Operator struct {
+	Precedence       int
+	RightAssociative bool
+}
+
+// Style controls how LaTeX and MathML print a tree, so that dialects with
+// their own operators can be printed with the right grouping.
+type Style struct {
+	// Binary holds the precedence and associativity of each binary operator.
+	// Operands of operators which aren't listed are always parenthesised.
+	Binary map[string]Operator
+
+	// Fractions lays out "/" as a fraction, Powers lays out "^" as a
+	// superscript and Products writes "*" as a dot.
+	Fractions, Powers, Products bool
+}
+
+// DefaultStyle returns the style for the default operators of the parser.
+// The map is new on every call, so it can be changed freely.
+func DefaultStyle() Style {
+	return Style{
+		Binary: map[string]Operator{
+			"+": {Precedence: 1},
+			"-": {Precedence: 1},
+			"*": {Precedence: 2},
+			"/": {Precedence: 2},
+			"^": {Precedence: 3, RightAssociative: true},
+		},
+		Fractions: true,
+		Powers:    true,
+		Products:  true,
+	}
+}
+
+// laidOut returns whether n is grouped by its layout, so that it never needs
+// parentheses.
+func (s Style) laidOut(n Node) bool {
+	b, ok := n.(BinaryExpr)
+	return ok && ((s.Fractions && b.Op == "/") || (s.Powers && b.Op == "^"))
+}
+
+// unaryNeedsParens returns whether the operand n of a unary operator needs to
+// be wrapped in parentheses. Only plain values are left alone, so that "5! !"
+// isn't mistaken for "5!!".
+func unaryNeedsParens(n Node) bool {
+	switch n := n.(type) {
+	case Lit:
+		return n.signed()
+	case BadExpr:
+		return false
+	default:
+		return true
+	}
+}
+
+// needsParens returns whether the operand n of the binary operator op needs
+// to be wrapped in parentheses to keep its grouping when written infix.
+func (s Style) needsParens(op string, n Node, right bool) bool {
+	switch n := n.(type) {
+	case Lit:
+		// Signs are only unambiguous at the very start of an expression.
+		return right && n.signed()
+	case UnaryExpr:
+		// Postfix operators are assumed to bind tighter than binary operators,
+		// but prefix operators are ambiguous on the right just like signs.
+		return right && !n.Postfix
+	case BadExpr:
+		return false
+	case BinaryExpr:
+		parent, ok := s.Binary[op]
+		child, childOk := s.Binary[n.Op]
+
+		// Without a precedence we can't tell, so play it safe.
+		if !ok || !childOk {
+			return true
+		}
+
+		if child.Precedence != parent.Precedence {
+			return child.Precedence < parent.Precedence
+		}
+
+		// Operators of the same precedence group towards their associativity.
+		return right != parent.RightAssociative
+	default:
+		return true
+	}
+}
@@ -0,0 +1,27 @@
+package ast
+
+import "fmt"
+
+// RPN returns the tree rooted at n in reverse Polish notation, such as
+// "3 4 + 2 *". Prefix operators are written in parentheses, such as
+// "x 1 + (-)", so that they can't be mistaken for binary operators.
+func RPN(n Node) string {
+	switch n := n.(type) {
+	case Lit:
+		return n.Value
+	case UnaryExpr:
+		// Unary operators are written after their operand, whichever side
+		// they're written on infix.
+		if n.Postfix {
+			return RPN(n.X) + " " + n.Op
+		}
+
+		return RPN(n.X) + " (" + n.Op + ")"
+	case BadExpr:
+		return n.String()
+	case BinaryExpr:
+		return RPN(n.Left) + " " + RPN(n.Right) + " " + n.Op
+	default:
+		panic(fmt.Sprintf("ast.RPN: unexpected node type %T", n))
+	}
+}
@@ -0,0 +1,10 @@
+package ast_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"testing"
+)
+
+func TestRPN(t *testing.T) {
+	testGolden(t, "rpn.golden", ast.RPN)
+}
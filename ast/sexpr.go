@@ -0,0 +1,49 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SExpr returns the tree rooted at n as an S-expression, such as
+// "(+ 1 (* 2 3))". Left associated chains of the same operator are written as
+// a single list, so "(1 - 2) - 3" is written as "(- 1 2 3)".
+func SExpr(n Node) string {
+	switch n := n.(type) {
+	case Lit:
+		return n.Value
+	case UnaryExpr:
+		return "(" + n.Op + " " + SExpr(n.X) + ")"
+	case BadExpr:
+		return n.String()
+	case BinaryExpr:
+		// Collect the operands of the chain from right to left.
+		operands := []string{SExpr(n.Right)}
+		left := n.Left
+
+		for {
+			b, ok := left.(BinaryExpr)
+			if !ok || b.Op != n.Op {
+				break
+			}
+
+			operands = append(operands, SExpr(b.Right))
+			left = b.Left
+		}
+
+		operands = append(operands, SExpr(left))
+
+		b := strings.Builder{}
+		b.WriteString("(" + n.Op)
+
+		for i := len(operands) - 1; i >= 0; i-- {
+			b.WriteString(" " + operands[i])
+		}
+
+		b.WriteString(")")
+
+		return b.String()
+	default:
+		panic(fmt.Sprintf("ast.SExpr: unexpected node type %T", n))
+	}
+}
@@ -0,0 +1,10 @@
+package ast_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"testing"
+)
+
+func TestSExpr(t *testing.T) {
+	testGolden(t, "sexpr.golden", ast.SExpr)
+}
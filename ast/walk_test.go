@@ -0,0 +1,100 @@
+package ast_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+	"reflect"
+	"testing"
+)
+
+// (1 + Pi) * -x
+var walkTree = ast.BinaryExpr{
+	Left: ast.BinaryExpr{
+		Left:  ast.Lit{Type: token.NumberToken, Value: "1"},
+		Right: ast.Lit{Type: token.ConstantToken, Value: "Pi"},
+		Op:    "+",
+	},
+	Right: ast.Lit{Type: token.ConstantToken, Value: "-x"},
+	Op:    "*",
+}
+
+type recorder struct {
+	visited *[]string
+}
+
+func (r recorder) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*r.visited = append(*r.visited, "end")
+	} else {
+		*r.visited = append(*r.visited, node.String())
+	}
+
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	var visited []string
+	ast.Walk(recorder{&visited}, walkTree)
+
+	expected := []string{
+		"((1 + Pi) * -x)",
+		"(1 + Pi)",
+		"1",
+		"end",
+		"Pi",
+		"end",
+		"end",
+		"-x",
+		"end",
+		"end",
+	}
+
+	if !reflect.DeepEqual(visited, expected) {
+		t.Fatalf("expected %q but got %q", expected, visited)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var constants []string
+	var operations int
+
+	ast.Inspect(walkTree, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case ast.Lit:
+			if n.Type == token.ConstantToken {
+				constants = append(constants, n.Value)
+			}
+		case ast.BinaryExpr:
+			operations++
+		}
+
+		return true
+	})
+
+	if expected := []string{"Pi", "-x"}; !reflect.DeepEqual(constants, expected) {
+		t.Errorf("expected constants %q but got %q", expected, constants)
+	}
+
+	if operations != 2 {
+		t.Errorf("expected 2 operations but got %d", operations)
+	}
+}
+
+func TestInspectSkip(t *testing.T) {
+	var visited []string
+
+	ast.Inspect(walkTree, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+
+		visited = append(visited, n.String())
+
+		// Don't descend into the left hand side.
+		return n.String() != "(1 + Pi)"
+	})
+
+	if expected := []string{"((1 + Pi) * -x)", "(1 + Pi)", "-x"}; !reflect.DeepEqual(visited, expected) {
+		t.Fatalf("expected %q but got %q", expected, visited)
+	}
+}
@@ -1,43 +1,217 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/ast/astutil"
 	"github.com/jackwilsdon/go-calc/evaluator"
 	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/snippet"
+	"github.com/jackwilsdon/go-calc/solve"
 	"math"
 	"os"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
+const usage = `usage: %[1]s [-q] [--ast=dot] [--deps] [--input format] [--output format] sum
+       %[1]s [-q] solve variable lhs = rhs
+  -q               output result or roots only
+  --ast=dot        output the syntax tree as a Graphviz graph
+  --deps           output the constants referenced by the sum and their positions
+  --input format   read the sum as infix (default) or rpn
+  --output format  output the sum as mathml or rpn instead of evaluating it
+`
+
 var constants = map[string]float64{
 	"Inf": math.Inf(1),
 	"Pi":  math.Pi,
 	"π":   math.Pi,
 }
 
+// inputs holds the parsers for each input format.
+var inputs = map[string]func(string) (ast.Node, error){
+	"infix": parser.ParseString,
+	"rpn":   parser.ParseRPNString,
+}
+
+// outputs holds the printers for each output format.
+var outputs = map[string]func(ast.Node) string{
+	"mathml": ast.MathML,
+	"rpn":    ast.RPN,
+}
+
+// isTerminal returns whether f is a terminal, rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// span returns the part of the source which err is about, if it has one.
+func span(err error) (int, int, bool) {
+	var pe *parser.Error
+	if errors.As(err, &pe) {
+		return pe.Pos, pe.Pos + utf8.RuneCountInString(pe.Token.Value), true
+	}
+
+	var pl *parser.LimitError
+	if errors.As(err, &pl) && pl.Pos >= 0 {
+		return pl.Pos, pl.Pos + 1, true
+	}
+
+	var ee *evaluator.Error
+	if errors.As(err, &ee) && ee.Node != nil {
+		// Point at the operator itself rather than everything it applies to.
+		switch n := ee.Node.(type) {
+		case ast.BinaryExpr:
+			return n.OpPos, n.OpPos + utf8.RuneCountInString(n.Op), true
+		case ast.UnaryExpr:
+			return n.OpPos, n.OpPos + utf8.RuneCountInString(n.Op), true
+		}
+
+		return ee.Pos, ee.Node.End(), true
+	}
+
+	var le *evaluator.LimitError
+	if errors.As(err, &le) && le.Node != nil {
+		return le.Pos, le.Node.End(), true
+	}
+
+	return 0, 0, false
+}
+
+// failure returns the message reporting that action failed on src because of
+// err, showing where the problem is if err has a position. Errors in
+// sources with more than one line also say which line and column they're on.
+func failure(action, src string, err error, colour bool) string {
+	message := fmt.Sprintf("failed to %s: %s\n", action, err)
+
+	if from, to, ok := span(err); ok {
+		if strings.ContainsRune(src, '\n') {
+			line, column := snippet.Position(src, from)
+			message += fmt.Sprintf("line %d, column %d:\n", line, column)
+		}
+
+		message += snippet.Format(src, from, to, colour)
+	}
+
+	return message
+}
+
+// failed reports that action failed on src because of err, and exits.
+func failed(action, src string, err error) {
+	// Errors are written to stderr, so that's what needs to be a terminal to
+	// show colours.
+	_, _ = fmt.Fprint(os.Stderr, failure(action, src, err, isTerminal(os.Stderr)))
+
+	os.Exit(1)
+}
+
+// value returns the value of the flag at the start of args, written as either
+// "--name=value" or "--name value", along with the remaining arguments.
+func value(args []string, name string) (string, []string, bool) {
+	if strings.HasPrefix(args[0], name+"=") {
+		return strings.TrimPrefix(args[0], name+"="), args[1:], true
+	}
+
+	if args[0] == name {
+		if len(args) == 1 {
+			_, _ = fmt.Fprintf(os.Stderr, "missing value for %s\n", name)
+			os.Exit(1)
+		}
+
+		return args[1], args[2:], true
+	}
+
+	return "", args, false
+}
+
 func main() {
 	args := os.Args[1:]
-	var quiet bool
-	if len(args) > 0 && args[0] == "-q" {
-		args = args[1:]
-		quiet = true
+	var quiet, deps bool
+	var astFormat, output string
+	input := "infix"
+	for len(args) > 0 {
+		if args[0] == "-q" {
+			args = args[1:]
+			quiet = true
+		} else if args[0] == "--deps" {
+			args = args[1:]
+			deps = true
+		} else if v, rest, ok := value(args, "--ast"); ok {
+			if v != "dot" {
+				_, _ = fmt.Fprintf(os.Stderr, "unsupported AST format %q\n", v)
+				os.Exit(1)
+			}
+			args, astFormat = rest, v
+		} else if v, rest, ok := value(args, "--input"); ok {
+			if _, ok := inputs[v]; !ok {
+				_, _ = fmt.Fprintf(os.Stderr, "unsupported input format %q\n", v)
+				os.Exit(1)
+			}
+			args, input = rest, v
+		} else if v, rest, ok := value(args, "--output"); ok {
+			if _, ok := outputs[v]; !ok {
+				_, _ = fmt.Fprintf(os.Stderr, "unsupported output format %q\n", v)
+				os.Exit(1)
+			}
+			args, output = rest, v
+		} else {
+			break
+		}
 	}
 	if len(args) == 0 {
-		_, _ = fmt.Fprintf(os.Stderr, "usage: %s [-q] sum\n  -q output result only\n", os.Args[0])
+		_, _ = fmt.Fprintf(os.Stderr, usage, os.Args[0])
 		os.Exit(1)
 	}
 
-	node, err := parser.ParseString(strings.Join(args, " "))
+	if args[0] == "solve" {
+		if len(args) < 3 {
+			_, _ = fmt.Fprintf(os.Stderr, usage, os.Args[0])
+			os.Exit(1)
+		}
+		solveEquation(args[1], strings.Join(args[2:], " "), quiet)
+		return
+	}
+
+	src := strings.Join(args, " ")
+
+	node, err := inputs[input](src)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "failed to parse: %s\n", err)
-		os.Exit(1)
+		failed("parse", src, err)
+	}
+
+	if astFormat == "dot" {
+		fmt.Print(ast.Dot(node))
+		return
+	}
+
+	if deps {
+		for _, ref := range astutil.Refs(node) {
+			if quiet {
+				fmt.Println(ref.Name)
+				continue
+			}
+
+			positions := make([]string, len(ref.Positions))
+			for i, p := range ref.Positions {
+				positions[i] = strconv.Itoa(p)
+			}
+			fmt.Printf("%s at %s\n", ref.Name, strings.Join(positions, ", "))
+		}
+		return
+	}
+
+	if output != "" {
+		fmt.Println(outputs[output](node))
+		return
 	}
 
 	result, err := evaluator.Evaluate(node, constants)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "failed to interpret: %s\n", err)
-		os.Exit(1)
+		failed("interpret", src, err)
 	}
 
 	formattedResult := strconv.FormatFloat(result, 'f', -1, 64)
@@ -47,3 +221,25 @@ func main() {
 		fmt.Printf("%s = %s\n", node, formattedResult)
 	}
 }
+
+// solveEquation prints the roots of equation in the variable x.
+func solveEquation(x, equation string, quiet bool) {
+	lhs, rhs, err := parser.ParseEquationString(equation)
+	if err != nil {
+		failed("parse", equation, err)
+	}
+
+	roots, err := solve.Solve(lhs, rhs, x, constants, solve.DefaultOptions())
+	if err != nil {
+		failed("solve", equation, err)
+	}
+
+	for _, root := range roots {
+		formattedRoot := strconv.FormatFloat(root, 'f', -1, 64)
+		if quiet {
+			fmt.Println(formattedRoot)
+		} else {
+			fmt.Printf("%s = %s\n", x, formattedRoot)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"strconv"
+	"testing"
+)
+
+func TestFailure(t *testing.T) {
+	cases := []struct {
+		action   string
+		src      string
+		evaluate bool
+		expected string
+	}{
+		{"parse", "1 + )", false, "failed to parse: unexpected \")\", expected a factor at 4\n1 + )\n    ^\n"},
+		{"parse", "1 +", false, "failed to parse: unexpected EOF, expected a factor\n1 +\n   ^\n"},
+		{"parse", "12 345", false, "failed to parse: unexpected trailing \"345\" at 3\n12 345\n   ^~~\n"},
+		{"interpret", "1 + -foo * 2", true, "failed to interpret: unknown constant \"foo\" at 4\n1 + -foo * 2\n    ^~~~\n"},
+		{"interpret", "1 + 2 ^ (3 - x)", true, "failed to interpret: unknown constant \"x\" at 13\n1 + 2 ^ (3 - x)\n             ^\n"},
+		{"interpret", "2 * 1..5", true, "failed to interpret: invalid number \"1..5\" at 4\n2 * 1..5\n    ^~~~\n"},
+		{"parse", "1 +\n)", false, "failed to parse: unexpected \")\", expected a factor at 4\nline 2, column 1:\n)\n^\n"},
+		{"interpret", "1 +\n2 * foo", true, "failed to interpret: unknown constant \"foo\" at 8\nline 2, column 5:\n2 * foo\n    ^~~\n"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.src)
+
+			if c.evaluate {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				_, err = evaluator.Evaluate(n, constants)
+			}
+
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			actual := failure(c.action, c.src, err, false)
+			if actual != c.expected {
+				t.Fatalf("expected %q but got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFailureWithoutPosition(t *testing.T) {
+	actual := failure("solve", "x = 1", errors.New("no roots"), false)
+
+	if actual != "failed to solve: no roots\n" {
+		t.Fatalf("expected only the error but got %q", actual)
+	}
+}
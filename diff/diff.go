@@ -0,0 +1,176 @@
+// Package diff implements symbolic differentiation of syntax trees.
+package diff
+
+import (
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/simplify"
+	"github.com/jackwilsdon/go-calc/token"
+	"math"
+	"strconv"
+)
+
+// Derive returns the derivative of n with respect to the constant x. The
+// derivative is simplified algebraically before it is returned.
+func Derive(n ast.Node, x string) (ast.Node, error) {
+	d, err := derive(n, x)
+	if err != nil {
+		return nil, err
+	}
+
+	// A nil derivative means that n doesn't depend on x.
+	if d == nil {
+		zero, _ := ast.NewNumberAt(0, n.Pos())
+		return zero, nil
+	}
+
+	return simplify.Simplify(d, simplify.Algebraic), nil
+}
+
+// number returns a number literal for v positioned at pos, for values which
+// are known to be finite.
+func number(v float64, pos int) ast.Lit {
+	l, _ := ast.NewNumberAt(v, pos)
+	return l
+}
+
+// derive returns the unsimplified derivative of n with respect to x, or nil
+// if the derivative is zero.
+func derive(n ast.Node, x string) (ast.Node, error) {
+	switch n := n.(type) {
+	case ast.Lit:
+		if n.Type != token.ConstantToken || n.Unsigned() != x {
+			return nil, nil
+		}
+
+		if n.Negative() {
+			return number(-1, n.Pos()), nil
+		}
+
+		return number(1, n.Pos()), nil
+	case ast.BinaryExpr:
+		return deriveBinary(n, x)
+	case ast.UnaryExpr:
+		return deriveUnary(n, x)
+	case ast.BadExpr:
+		return nil, fmt.Errorf("bad expression at %d", n.From)
+	default:
+		return nil, fmt.Errorf("unknown node %T", n)
+	}
+}
+
+func deriveUnary(u ast.UnaryExpr, x string) (ast.Node, error) {
+	d, err := derive(u.X, x)
+	if err != nil {
+		return nil, err
+	}
+
+	// Whatever the operator does, it's constant if its operand is.
+	if d == nil {
+		return nil, nil
+	}
+
+	switch u.Op {
+	case "%":
+		// (a%)' = a' / 100
+		return ast.BinaryExpr{Left: d, Right: number(100, u.OpPos), Op: "/", OpPos: u.OpPos}, nil
+	case "!", "!!":
+		// The derivative of the gamma function can't be written without the
+		// digamma function.
+		return nil, fmt.Errorf("cannot differentiate %s with respect to %s at %d", u, x, u.OpPos)
+	default:
+		return nil, fmt.Errorf("cannot differentiate unsupported operation %s at %d", u.Op, u.OpPos)
+	}
+}
+
+func deriveBinary(b ast.BinaryExpr, x string) (ast.Node, error) {
+	a, c := b.Left, b.Right
+
+	da, err := derive(a, x)
+	if err != nil {
+		return nil, err
+	}
+
+	dc, err := derive(c, x)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both sides are constant with respect to x.
+	if da == nil && dc == nil {
+		return nil, nil
+	}
+
+	pos := b.OpPos
+
+	binary := func(left ast.Node, op string, right ast.Node) ast.Node {
+		return ast.BinaryExpr{Left: left, Right: right, Op: op, OpPos: pos}
+	}
+
+	switch b.Op {
+	case "+":
+		if da == nil {
+			return dc, nil
+		} else if dc == nil {
+			return da, nil
+		}
+
+		return binary(da, "+", dc), nil
+	case "-":
+		if da == nil {
+			return ast.Negate(dc, pos), nil
+		} else if dc == nil {
+			return da, nil
+		}
+
+		return binary(da, "-", dc), nil
+	case "*":
+		// (ac)' = a'c + ac'
+		if da == nil {
+			return binary(a, "*", dc), nil
+		} else if dc == nil {
+			return binary(da, "*", c), nil
+		}
+
+		return binary(binary(da, "*", c), "+", binary(a, "*", dc)), nil
+	case "/":
+		// (a/c)' = (a'c - ac') / c^2
+		if dc == nil {
+			return binary(da, "/", c), nil
+		}
+
+		squared := binary(c, "^", number(2, pos))
+
+		if da == nil {
+			return ast.Negate(binary(binary(a, "*", dc), "/", squared), pos), nil
+		}
+
+		return binary(binary(binary(da, "*", c), "-", binary(a, "*", dc)), "/", squared), nil
+	case "^":
+		// (a^c)' = c a^(c-1) a' when c doesn't depend on x.
+		if dc == nil {
+			power := binary(a, "^", binary(c, "-", number(1, pos)))
+			return binary(binary(c, "*", power), "*", da), nil
+		}
+
+		// (a^c)' = a^c ln(a) c' when a doesn't depend on x. Without a
+		// logarithm function, ln(a) can only be written out if a is a
+		// number.
+		if da == nil {
+			if l, ok := simplify.Simplify(a, simplify.Float).(ast.Lit); ok && l.Type == token.NumberToken {
+				v, err := strconv.ParseFloat(l.Value, 64)
+				if err != nil {
+					return nil, err
+				}
+
+				if ln := math.Log(v); !math.IsNaN(ln) && !math.IsInf(ln, 0) {
+					return binary(binary(b, "*", number(ln, pos)), "*", dc), nil
+				}
+			}
+		}
+
+		return nil, fmt.Errorf("cannot differentiate %s with respect to %s at %d", b, x, pos)
+	default:
+		return nil, fmt.Errorf("cannot differentiate unsupported operation %s at %d", b.Op, pos)
+	}
+}
@@ -0,0 +1,120 @@
+package diff_test
+
+import (
+	"github.com/jackwilsdon/go-calc/diff"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	cases := []struct {
+		s, expected string
+	}{
+		{"5", "0"},
+		{"y", "0"},
+		{"x", "1"},
+		{"-x", "-1"},
+		{"x + y", "1"},
+		{"y - x", "-1"},
+		{"3 * x", "3"},
+		{"x * x", "(2 * x)"},
+		{"x ^ 3", "(3 * (x ^ 2))"},
+		{"x ^ 3 + 2 * x - 7", "((3 * (x ^ 2)) + 2)"},
+		{"y / x", "(-y / (x ^ 2))"},
+		{"x / 2", "0.5"},
+		{"x%", "0.01"},
+		{"5! * x", "120"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := diff.Derive(n, "x")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d.String() != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, d)
+			}
+		})
+	}
+}
+
+// TestDeriveFiniteDifference checks derivatives against central finite
+// differences.
+func TestDeriveFiniteDifference(t *testing.T) {
+	expressions := []string{
+		"x ^ 3 + 2 * x",
+		"x * y / (x + 1)",
+		"2 ^ x",
+		"(x ^ 2 + 1) ^ 3",
+		"x / x ^ 2 - 3",
+		"Pi * x ^ 0.5",
+		"-x * -x - x / y",
+		"(1 + x) ^ 10 * 1000 - 2000",
+		"(x - 1) / (x + 1) * (y - x)",
+		"(x ^ 2)% + 3!",
+	}
+
+	points := []float64{0.5, 1.3, 2.7, 4}
+
+	for i, s := range expressions {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := diff.Derive(n, "x")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := func(x float64) float64 {
+				v, err := evaluator.Evaluate(n, map[string]float64{"x": x, "y": 1.7, "Pi": math.Pi})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return v
+			}
+
+			for _, x := range points {
+				actual, err := evaluator.Evaluate(d, map[string]float64{"x": x, "y": 1.7, "Pi": math.Pi})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				h := 1e-6 * math.Max(1, math.Abs(x))
+				expected := (f(x+h) - f(x-h)) / (2 * h)
+
+				if math.Abs(actual-expected) > 1e-5*math.Max(1, math.Abs(expected)) {
+					t.Errorf("x=%v: %s gave %v but expected %v", x, d, actual, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestDeriveUnsupported(t *testing.T) {
+	for i, s := range []string{"x ^ x", "y ^ x", "(0 - 2) ^ x", "x!", "(2 * x)!!"} {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := diff.Derive(n, "x"); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package evaluator
+
+import (
+	"context"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+	"math"
+)
+
+// dual is a dual number, holding a value along with its partial derivatives
+// with respect to each variable.
+type dual struct {
+	v float64
+	d []float64
+}
+
+// combine returns the partial derivatives a*x + b*y.
+func combine(a float64, x []float64, b float64, y []float64) []float64 {
+	d := make([]float64, len(x))
+
+	for i := range d {
+		// Skip zero derivatives so that infinite values don't turn them into
+		// NaN.
+		if x[i] != 0 {
+			d[i] += a * x[i]
+		}
+
+		if y[i] != 0 {
+			d[i] += b * y[i]
+		}
+	}
+
+	return d
+}
+
+// isZero returns whether all of the partial derivatives are zero.
+func isZero(d []float64) bool {
+	for _, v := range d {
+		if v != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dualOp performs the operation of n against two dual numbers.
+func dualOp(a, b dual, n ast.BinaryExpr) (dual, error) {
+	v, err := defaultEnv.op(a.v, b.v, n)
+	if err != nil {
+		return dual{}, err
+	}
+
+	switch n.Op {
+	case "+":
+		return dual{v, combine(1, a.d, 1, b.d)}, nil
+	case "-":
+		return dual{v, combine(1, a.d, -1, b.d)}, nil
+	case "*":
+		return dual{v, combine(b.v, a.d, a.v, b.d)}, nil
+	case "/":
+		return dual{v, combine(1/b.v, a.d, -a.v/(b.v*b.v), b.d)}, nil
+	case "^":
+		// The general rule involves ln(a), which isn't defined for negative
+		// bases, so use the power rule when the exponent is constant.
+		if isZero(b.d) {
+			return dual{v, combine(b.v*math.Pow(a.v, b.v-1), a.d, 0, b.d)}, nil
+		}
+
+		return dual{v, combine(v*b.v/a.v, a.d, v*math.Log(a.v), b.d)}, nil
+	default:
+		return dual{}, &Error{Kind: UnsupportedOperation, Pos: n.OpPos, Node: n}
+	}
+}
+
+// dualUnary performs the operation of n against a dual number.
+func dualUnary(x dual, n ast.UnaryExpr) (dual, error) {
+	v, err := defaultEnv.unary(x.v, n)
+	if err != nil {
+		return dual{}, err
+	}
+
+	switch n.Op {
+	case "!":
+		// d/dx Γ(x+1) = Γ(x+1)ψ(x+1)
+		return dual{v, combine(v*digamma(x.v+1), x.d, 0, x.d)}, nil
+	case "!!":
+		// Differentiate the logarithm of the extension used by
+		// doubleFactorial, which holds for integers too.
+		d := math.Ln2/2 + digamma(x.v/2+1)/2 + math.Pi*math.Sin(math.Pi*x.v)/4*math.Log(2/math.Pi)
+		return dual{v, combine(v*d, x.d, 0, x.d)}, nil
+	case "%":
+		return dual{v, combine(0.01, x.d, 0, x.d)}, nil
+	default:
+		return dual{}, &Error{Kind: UnsupportedOperation, Pos: n.OpPos, Node: n}
+	}
+}
+
+// evaluateDual evaluates n, which is depth levels into the tree, with each of
+// the variables at index i in vars having a partial derivative of 1 in
+// position i.
+func (e *evaluation) evaluateDual(n ast.Node, vars map[string]int, size, depth int) (dual, error) {
+	if err := e.enter(n, depth); err != nil {
+		return dual{}, err
+	}
+
+	switch n := n.(type) {
+	case ast.BinaryExpr:
+		left, err := e.evaluateDual(n.Left, vars, size, depth+1)
+		if err != nil {
+			return dual{}, err
+		}
+
+		right, err := e.evaluateDual(n.Right, vars, size, depth+1)
+		if err != nil {
+			return dual{}, err
+		}
+
+		return dualOp(left, right, n)
+	case ast.UnaryExpr:
+		x, err := e.evaluateDual(n.X, vars, size, depth+1)
+		if err != nil {
+			return dual{}, err
+		}
+
+		return dualUnary(x, n)
+	case ast.Lit:
+		v, err := literal(n, e.env.Constants)
+		if err != nil {
+			return dual{}, err
+		}
+
+		d := make([]float64, size)
+
+		if i, ok := vars[n.Unsigned()]; ok && n.Type == token.ConstantToken {
+			d[i] = 1
+
+			if n.Negative() {
+				d[i] = -1
+			}
+		}
+
+		return dual{v, d}, nil
+	case ast.BadExpr:
+		return dual{}, &Error{Kind: BadExpression, Pos: n.From, Node: n}
+	default:
+		return dual{}, &Error{Kind: UnknownNode, Node: n}
+	}
+}
+
+// Gradient returns the result of evaluating n with the provided constants,
+// along with the partial derivatives of the result with respect to each of
+// the named variables. Variables are looked up in constants like any other
+// constant. Trees deeper than DefaultMaxDepth, or with more than
+// DefaultMaxOperations nodes, give a LimitError.
+//
+// Derivatives are only known for the default operators, so n is always
+// evaluated with their default meanings, and any other operator gives an
+// UnsupportedOperation error.
+func Gradient(n ast.Node, constants map[string]float64, variables []string) (float64, []float64, error) {
+	env := defaultEnv
+	env.Constants = constants
+
+	return GradientContext(context.Background(), n, env, variables)
+}
+
+// GradientContext returns the result and partial derivatives of n like
+// Gradient, but with the constants and limits in env, stopping with a
+// LimitError if ctx is done before evaluation finishes. The operators in env
+// aren't used, as n is always evaluated with the default operators.
+func GradientContext(ctx context.Context, n ast.Node, env Env, variables []string) (float64, []float64, error) {
+	vars := make(map[string]int, len(variables))
+
+	for i, v := range variables {
+		vars[v] = i
+	}
+
+	e := evaluation{ctx: ctx, env: env}
+
+	result, err := e.evaluateDual(n, vars, len(variables), 1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return result.v, result.d, nil
+}
@@ -0,0 +1,82 @@
+package evaluator_test
+
+import (
+	"github.com/jackwilsdon/go-calc/diff"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// TestGradient checks gradients against symbolic derivatives.
+func TestGradient(t *testing.T) {
+	expressions := []string{
+		"x",
+		"-x + 3",
+		"x * y - y / x",
+		"x ^ 3 + y ^ 2",
+		"2 ^ x * y",
+		"(x + y) ^ 0.5 / Pi",
+		"(1 + x) ^ 10 * 1000 - 2000",
+		"-y * -x * (x - 1) / (y + 1)",
+		"x% * y + 3!",
+	}
+
+	variables := []string{"x", "y"}
+	points := [][2]float64{{0.5, 1.5}, {1.3, 0.2}, {2.7, 3}, {4, -0.5}}
+
+	for i, s := range expressions {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, p := range points {
+				constants := map[string]float64{"x": p[0], "y": p[1], "Pi": math.Pi}
+
+				value, gradient, err := evaluator.Gradient(n, constants, variables)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expectedValue, err := evaluator.Evaluate(n, constants)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if value != expectedValue {
+					t.Errorf("%v: expected value %v but got %v", p, expectedValue, value)
+				}
+
+				for j, v := range variables {
+					d, err := diff.Derive(n, v)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					expected, err := evaluator.Evaluate(d, constants)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if math.Abs(gradient[j]-expected) > 1e-9*math.Max(1, math.Abs(expected)) {
+						t.Errorf("%v: expected d/d%s to be %v but got %v", p, v, expected, gradient[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGradientUnknownConstant(t *testing.T) {
+	n, err := parser.ParseString("x * z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := evaluator.Gradient(n, map[string]float64{"x": 1}, []string{"x"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,100 @@
+package evaluator
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"math"
+)
+
+// Env holds everything needed to evaluate a tree: the values of constants and
+// the meaning of each operator. The operators should match those given to
+// the parser.
+type Env struct {
+	Constants map[string]float64
+	Binary    map[string]func(a, b float64) float64
+	Prefix    map[string]func(x float64) float64
+	Postfix   map[string]func(x float64) float64
+
+	// MaxDepth is the deepest tree which can be evaluated, or zero for no
+	// limit.
+	MaxDepth int
+
+	// MaxOperations is the most nodes which can be evaluated, or zero for no
+	// limit.
+	MaxOperations int
+}
+
+// binary holds the default binary operators.
+var binary = map[string]func(a, b float64) float64{
+	"+": func(a, b float64) float64 { return a + b },
+	"-": func(a, b float64) float64 { return a - b },
+	"*": func(a, b float64) float64 { return a * b },
+	"/": func(a, b float64) float64 { return a / b },
+	"^": math.Pow,
+}
+
+// postfix holds the default postfix operators.
+var postfix = map[string]func(x float64) float64{
+	"!":  factorial,
+	"!!": doubleFactorial,
+	"%":  func(x float64) float64 { return x / 100 },
+}
+
+// defaultEnv holds the default operators and limits, without any constants.
+var defaultEnv = Env{
+	Binary:        binary,
+	Postfix:       postfix,
+	MaxDepth:      DefaultMaxDepth,
+	MaxOperations: DefaultMaxOperations,
+}
+
+// NewEnv returns an environment with the provided constants and the default
+// operators, which match parser.DefaultOptions, limited to DefaultMaxDepth and
+// DefaultMaxOperations.
+// The operator maps are new on every call, so operators can be added, removed
+// or overridden in them freely.
+func NewEnv(constants map[string]float64) Env {
+	e := Env{
+		Constants: constants,
+		Binary:    map[string]func(a, b float64) float64{},
+		Prefix:    map[string]func(x float64) float64{},
+		Postfix:   map[string]func(x float64) float64{},
+
+		MaxDepth:      DefaultMaxDepth,
+		MaxOperations: DefaultMaxOperations,
+	}
+
+	for k, v := range binary {
+		e.Binary[k] = v
+	}
+
+	for k, v := range postfix {
+		e.Postfix[k] = v
+	}
+
+	return e
+}
+
+// op performs the operation of n against two values.
+func (e Env) op(a, b float64, n ast.BinaryExpr) (float64, error) {
+	f, ok := e.Binary[n.Op]
+	if !ok {
+		return 0, &Error{Kind: UnsupportedOperation, Pos: n.OpPos, Node: n}
+	}
+
+	return f(a, b), nil
+}
+
+// unary performs the operation of n against a value.
+func (e Env) unary(x float64, n ast.UnaryExpr) (float64, error) {
+	ops := e.Prefix
+	if n.Postfix {
+		ops = e.Postfix
+	}
+
+	f, ok := ops[n.Op]
+	if !ok {
+		return 0, &Error{Kind: UnsupportedOperation, Pos: n.OpPos, Node: n}
+	}
+
+	return f(x), nil
+}
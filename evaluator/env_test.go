@@ -0,0 +1,85 @@
+package evaluator_test
+
+import (
+	"errors"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestEvaluateEnv(t *testing.T) {
+	o := parser.DefaultOptions()
+	o.Binary["^"] = parser.Operator{Precedence: 1, Associativity: parser.LeftAssociative}
+	o.Binary["**"] = parser.Operator{Precedence: 3, Associativity: parser.RightAssociative}
+	o.Prefix["~"] = parser.Operator{Precedence: 4}
+	o.Postfix["!"] = parser.Operator{Precedence: 5}
+
+	env := evaluator.NewEnv(map[string]float64{"x": 6})
+	env.Binary["^"] = func(a, b float64) float64 { return float64(int64(a) ^ int64(b)) }
+	env.Binary["**"] = math.Pow
+	env.Prefix["~"] = func(x float64) float64 { return float64(^int64(x)) }
+	env.Postfix["!"] = func(x float64) float64 { return math.Gamma(x + 1) }
+
+	cases := []struct {
+		s        string
+		expected float64
+	}{
+		{"5 ^ 3", 6},
+		{"x ^ 2 * 2", 2},
+		{"2 ** 3 ** 2", 512},
+		{"~x", -7},
+		{"~0 ^ x", -7},
+		{"3! + 1", 7},
+		{"2 ** 3!", 64},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := o.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := evaluator.EvaluateEnv(n, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != c.expected {
+				t.Fatalf("expected %v but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEvaluateEnvUnsupported(t *testing.T) {
+	o := parser.DefaultOptions()
+	o.Postfix["?"] = parser.Operator{Precedence: 5}
+
+	n, err := o.ParseString("1 + 3?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = evaluator.Evaluate(n, nil)
+
+	var e *evaluator.Error
+	if !errors.As(err, &e) || e.Kind != evaluator.UnsupportedOperation || e.Pos != 5 {
+		t.Fatalf("expected an unsupported operation at 5 but got %v", err)
+	}
+
+	if err.Error() != "unsupported operation ? at 5" {
+		t.Fatalf("expected error %q but got %q", "unsupported operation ? at 5", err)
+	}
+}
+
+func TestNewEnvCopy(t *testing.T) {
+	env := evaluator.NewEnv(nil)
+	delete(env.Binary, "+")
+
+	if v, err := evaluator.Evaluate(mustParse(t, "1 + 2"), nil); err != nil || v != 3 {
+		t.Fatalf("expected changes to an environment not to affect the defaults but got %v, %v", v, err)
+	}
+}
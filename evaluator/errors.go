@@ -0,0 +1,110 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"strconv"
+)
+
+// Kind is the kind of problem described by an Error. Kinds are errors
+// themselves so that errors can be matched by kind using errors.Is, such as
+// errors.Is(err, evaluator.UnknownConstant).
+type Kind int
+
+const (
+	// UnsupportedOperation is an operator which can't be evaluated.
+	UnsupportedOperation Kind = iota + 1
+
+	// UnknownConstant is a constant which hasn't been given a value.
+	UnknownConstant
+
+	// UnknownLiteral is a literal which isn't a number or a constant.
+	UnknownLiteral
+
+	// InvalidNumber is a number literal which can't be read.
+	InvalidNumber
+
+	// BadExpression is an ast.BadExpr left behind by a syntax error.
+	BadExpression
+
+	// UnknownNode is a node of a type which can't be evaluated.
+	UnknownNode
+)
+
+var kindNames = map[Kind]string{
+	UnsupportedOperation: "unsupported operation",
+	UnknownConstant:      "unknown constant",
+	UnknownLiteral:       "unknown literal type",
+	InvalidNumber:        "invalid number",
+	BadExpression:        "bad expression",
+	UnknownNode:          "unknown node",
+}
+
+func (k Kind) Error() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+
+	return "unknown error kind " + strconv.Itoa(int(k))
+}
+
+// Error is a problem found while evaluating a tree.
+type Error struct {
+	Kind Kind
+
+	// Pos is the position of the problem in the source, such as the position
+	// of an unsupported operator, or the start of a literal including any
+	// sign.
+	Pos int
+
+	// Node is the offending node.
+	Node ast.Node
+
+	// Err is the underlying error, if there is one.
+	Err error
+}
+
+func (e *Error) Error() string {
+	switch n := e.Node.(type) {
+	case ast.BinaryExpr:
+		if e.Kind == UnsupportedOperation {
+			return fmt.Sprintf("unsupported operation %s at %d", n.Op, e.Pos)
+		}
+	case ast.UnaryExpr:
+		if e.Kind == UnsupportedOperation {
+			return fmt.Sprintf("unsupported operation %s at %d", n.Op, e.Pos)
+		}
+	case ast.Lit:
+		switch e.Kind {
+		case UnknownConstant:
+			return fmt.Sprintf("unknown constant %q at %d", n.Unsigned(), e.Pos)
+		case UnknownLiteral:
+			return fmt.Sprintf("unknown literal type %s (%d) at %d", n.Type, n.Type, e.Pos)
+		case InvalidNumber:
+			return fmt.Sprintf("invalid number %q at %d", n.Value, e.Pos)
+		}
+	}
+
+	// Unknown nodes are only ever nil, as ast.Node can't be implemented
+	// outside of the ast package, so there's no position to give.
+	if e.Kind == UnknownNode {
+		return fmt.Sprintf("unknown node %T", e.Node)
+	}
+
+	if e.Err != nil {
+		return fmt.Sprintf("%s at %d", e.Err, e.Pos)
+	}
+
+	return fmt.Sprintf("%s at %d", e.Kind, e.Pos)
+}
+
+// Is returns whether target is the kind of e.
+func (e *Error) Is(target error) bool {
+	k, ok := target.(Kind)
+
+	return ok && k == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,83 @@
+package evaluator_test
+
+import (
+	"errors"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/token"
+	"strconv"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	cases := []struct {
+		n    ast.Node
+		kind evaluator.Kind
+		pos  int
+		err  string
+	}{
+		{mustParse(t, "1 + -x"), evaluator.UnknownConstant, 4, `unknown constant "x" at 4`},
+		{mustParse(t, "1 + 2 * Pi"), evaluator.UnknownConstant, 8, `unknown constant "Pi" at 8`},
+		{
+			ast.BinaryExpr{Left: lit("1"), Right: lit("2"), Op: "%", OpPos: 2},
+			evaluator.UnsupportedOperation,
+			2,
+			"unsupported operation % at 2",
+		},
+		{ast.BadExpr{From: 3, To: 5}, evaluator.BadExpression, 3, "bad expression at 3"},
+		{ast.Lit{Type: token.ParenthesisToken, Value: "(", ValuePos: 4}, evaluator.UnknownLiteral, 4, "unknown literal type Parenthesis (1) at 4"},
+		{lit("1.2.3"), evaluator.InvalidNumber, 0, `invalid number "1.2.3" at 0`},
+		{mustParse(t, "2 *  -1..5"), evaluator.InvalidNumber, 5, `invalid number "-1..5" at 5`},
+		{ast.UnaryExpr{X: lit("1"), Op: "?", OpPos: 3, Postfix: true}, evaluator.UnsupportedOperation, 3, "unsupported operation ? at 3"},
+		{nil, evaluator.UnknownNode, 0, "unknown node <nil>"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, err := evaluator.Evaluate(c.n, nil)
+
+			var e *evaluator.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected an *evaluator.Error but got %T (%v)", err, err)
+			}
+
+			if e.Kind != c.kind {
+				t.Errorf("expected kind %q but got %q", c.kind, e.Kind)
+			}
+
+			if !errors.Is(err, c.kind) {
+				t.Errorf("expected error to be %q", c.kind)
+			}
+
+			if e.Pos != c.pos {
+				t.Errorf("expected position %d but got %d", c.pos, e.Pos)
+			}
+
+			if err.Error() != c.err {
+				t.Errorf("expected error %q but got %q", c.err, err)
+			}
+		})
+	}
+}
+
+func TestInvalidNumberUnwrap(t *testing.T) {
+	_, err := evaluator.Evaluate(lit("1.2.3"), nil)
+
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("expected %v to wrap strconv.ErrSyntax", err)
+	}
+}
+
+func mustParse(t *testing.T, s string) ast.Node {
+	n, err := parser.ParseString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func lit(v string) ast.Lit {
+	return ast.Lit{Type: token.NumberToken, Value: v}
+}
@@ -1,73 +1,139 @@
 package evaluator
 
 import (
-	"fmt"
+	"context"
 	"github.com/jackwilsdon/go-calc/ast"
 	"github.com/jackwilsdon/go-calc/token"
-	"math"
 	"strconv"
 )
 
-// op performs a named operation against two values.
-func op(a, b float64, op string) (float64, error) {
-	switch op {
-	case "+":
-		return a + b, nil
-	case "-":
-		return a - b, nil
-	case "*":
-		return a * b, nil
-	case "/":
-		return a / b, nil
-	case "^":
-		return math.Pow(a, b), nil
+// literal returns the value of l with the provided constants.
+func literal(l ast.Lit, constants map[string]float64) (float64, error) {
+	switch l.Type {
+	case token.NumberToken:
+		v, err := strconv.ParseFloat(l.Value, 64)
+		if err != nil {
+			return 0, &Error{Kind: InvalidNumber, Pos: l.Pos(), Node: l, Err: err}
+		}
+		return v, nil
+	case token.ConstantToken:
+		key := l.Value
+		if key[0] == '+' || key[0] == '-' {
+			key = key[1:]
+		}
+		v, ok := constants[key]
+		if !ok {
+			return 0, &Error{Kind: UnknownConstant, Pos: l.Pos(), Node: l}
+		}
+		if l.Value[0] == '-' {
+			return -v, nil
+		}
+		return v, nil
 	default:
-		return 0, fmt.Errorf("unsupported operation: %s", op)
+		return 0, &Error{Kind: UnknownLiteral, Pos: l.Pos(), Node: l}
 	}
 }
 
-// Evaluate returns the result of evaluating n with the provided constants.
+// Evaluate returns the result of evaluating n with the provided constants and
+// the default operators and limits.
 func Evaluate(n ast.Node, constants map[string]float64) (float64, error) {
+	env := defaultEnv
+	env.Constants = constants
+
+	return EvaluateEnv(n, env)
+}
+
+// EvaluateEnv returns the result of evaluating n in env.
+func EvaluateEnv(n ast.Node, env Env) (float64, error) {
+	return EvaluateContext(context.Background(), n, env)
+}
+
+// EvaluateContext returns the result of evaluating n in env, stopping with a
+// LimitError if ctx is done before evaluation finishes.
+func EvaluateContext(ctx context.Context, n ast.Node, env Env) (float64, error) {
+	e := evaluation{ctx: ctx, env: env}
+
+	return e.evaluate(n, 1)
+}
+
+// evaluation holds the state of a single call to EvaluateContext,
+// GradientContext or PartialEvalContext.
+type evaluation struct {
+	ctx        context.Context
+	env        Env
+	operations int
+}
+
+// enter counts n, which is depth levels into the tree, as an operation and
+// returns a LimitError if that exceeds one of the limits.
+func (e *evaluation) enter(n ast.Node, depth int) error {
+	if err := checkDepth(n, depth, e.env.MaxDepth); err != nil {
+		return err
+	}
+
+	e.operations++
+
+	if max := e.env.MaxOperations; max > 0 && e.operations > max {
+		return &LimitError{Limit: OperationLimit, Max: max, Pos: n.Pos(), Node: n}
+	}
+
+	select {
+	case <-e.ctx.Done():
+		return &LimitError{Limit: ContextLimit, Pos: n.Pos(), Node: n, Err: e.ctx.Err()}
+	default:
+	}
+
+	return nil
+}
+
+// evaluate returns the result of evaluating n, which is depth levels into the
+// tree.
+func (e *evaluation) evaluate(n ast.Node, depth int) (float64, error) {
+	env := e.env
+
+	if err := e.enter(n, depth); err != nil {
+		return 0, err
+	}
+
 	// Evaluate the left and right sides of binary expressions and then
 	// perform the described operation on them.
 	if b, ok := n.(ast.BinaryExpr); ok {
-		left, err := Evaluate(b.Left, constants)
+		left, err := e.evaluate(b.Left, depth+1)
 
 		if err != nil {
 			return 0, err
 		}
 
-		right, err := Evaluate(b.Right, constants)
+		right, err := e.evaluate(b.Right, depth+1)
 
 		if err != nil {
 			return 0, err
 		}
 
-		return op(left, right, b.Op)
+		return env.op(left, right, b)
+	}
+
+	// Evaluate the operand of unary expressions and then perform the
+	// operation on it.
+	if u, ok := n.(ast.UnaryExpr); ok {
+		x, err := e.evaluate(u.X, depth+1)
+
+		if err != nil {
+			return 0, err
+		}
+
+		return env.unary(x, u)
 	}
 
 	// We can interpret the value of a literal as a floating point number.
 	if l, ok := n.(ast.Lit); ok {
-		switch l.Type {
-		case token.NumberToken:
-			return strconv.ParseFloat(l.Value, 64)
-		case token.ConstantToken:
-			key := l.Value
-			if key[0] == '+' || key[0] == '-' {
-				key = key[1:]
-			}
-			v, ok := constants[key]
-			if !ok {
-				return 0, fmt.Errorf("unknown constant %q", key)
-			}
-			if l.Value[0] == '-' {
-				return -v, nil
-			}
-			return v, nil
-		default:
-			return 0, fmt.Errorf("unknown literal type %s (%d)", l.Type, l.Type)
-		}
+		return literal(l, env.Constants)
+	}
+
+	// Bad expressions were left behind by syntax errors, so have no value.
+	if b, ok := n.(ast.BadExpr); ok {
+		return 0, &Error{Kind: BadExpression, Pos: b.From, Node: b}
 	}
 
-	return 0, fmt.Errorf("unknown node %T", n)
+	return 0, &Error{Kind: UnknownNode, Node: n}
 }
@@ -0,0 +1,74 @@
+package evaluator
+
+import "math"
+
+// maxFactorial is the largest integer with a factorial which fits in a
+// float64.
+const maxFactorial = 170
+
+// factorial returns x!, using the gamma function for anything other than
+// non-negative integers. Negative integers give NaN.
+func factorial(x float64) float64 {
+	if x != math.Trunc(x) || x < 0 {
+		return math.Gamma(x + 1)
+	}
+
+	if x > maxFactorial {
+		return math.Inf(1)
+	}
+
+	// Multiply integers out so that the result is exact where it can be.
+	v := 1.0
+	for i := 2.0; i <= x; i++ {
+		v *= i
+	}
+
+	return v
+}
+
+// doubleFactorial returns x!!, the product of every integer from x down to 1
+// or 2 in steps of two. Anything other than integers from -1 up uses its
+// extension to the real numbers:
+//
+//	x!! = 2^(x/2) * Γ(x/2 + 1) * (2/π)^((1 - cos(πx)) / 4)
+func doubleFactorial(x float64) float64 {
+	if x != math.Trunc(x) || x < -1 {
+		return math.Pow(2, x/2) * math.Gamma(x/2+1) * math.Pow(2/math.Pi, (1-math.Cos(math.Pi*x))/4)
+	}
+
+	if x > 2*maxFactorial {
+		return math.Inf(1)
+	}
+
+	v := 1.0
+	for i := x; i > 1; i -= 2 {
+		v *= i
+	}
+
+	return v
+}
+
+// digamma returns ψ(x), the derivative of ln(Γ(x)).
+func digamma(x float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, -1) || (x <= 0 && x == math.Trunc(x)) {
+		return math.NaN()
+	}
+
+	// Reflect negative values into the positive half, where the series below
+	// converges.
+	if x < 0 {
+		return digamma(1-x) - math.Pi/math.Tan(math.Pi*x)
+	}
+
+	// Shift x up using ψ(x) = ψ(x+1) - 1/x until the asymptotic series is
+	// accurate.
+	var v float64
+	for ; x < 6; x++ {
+		v -= 1 / x
+	}
+
+	x2 := 1 / (x * x)
+
+	return v + math.Log(x) - 1/(2*x) -
+		x2*(1.0/12-x2*(1.0/120-x2*(1.0/252-x2*(1.0/240-x2*(1.0/132)))))
+}
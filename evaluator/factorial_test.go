@@ -0,0 +1,139 @@
+package evaluator_test
+
+import (
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestPostfix(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected float64
+	}{
+		{"0!", 1},
+		{"1!", 1},
+		{"5!", 120},
+		{"20!", 2432902008176640000},
+		{"170!", 7.257415615307994e306},
+		{"171!", math.Inf(1)},
+		{"0.5!", math.Sqrt(math.Pi) / 2},
+		{"1.5!", 3 * math.Sqrt(math.Pi) / 4},
+		{"3! ^ 2", 36},
+		{"2 ^ 3!", 64},
+		{"(1 + 2)!", 6},
+		{"3! !", 720},
+		{"0!!", 1},
+		{"1!!", 1},
+		{"5!!", 15},
+		{"6!!", 48},
+		{"(0 - 1)!!", 1},
+		{"20%", 0.2},
+		{"50 + 20%", 50.2},
+		{"200 * 15%", 30},
+		{"50%%", 0.005},
+		{"x! + x%", math.Gamma(5.5) + 0.045},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := evaluator.Evaluate(n, map[string]float64{"x": 4.5})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != c.expected && !(math.Abs(actual-c.expected) <= 1e-12*math.Abs(c.expected)) {
+				t.Fatalf("expected %v but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFactorialPoles(t *testing.T) {
+	// Signs belong to the number, so "-1!" is the factorial of -1.
+	for i, s := range []string{"-1!", "-3!", "-2!!"} {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := evaluator.Evaluate(n, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !math.IsNaN(actual) && !math.IsInf(actual, 0) {
+				t.Fatalf("expected NaN or an infinity but got %v", actual)
+			}
+		})
+	}
+}
+
+// TestDoubleFactorialExtension checks that the real extension of the double
+// factorial is continuous with the integer values.
+func TestDoubleFactorialExtension(t *testing.T) {
+	for _, v := range []float64{1, 2, 3, 4, 7, 10} {
+		exact, err := evaluator.Evaluate(mustParse(t, strconv.FormatFloat(v, 'f', -1, 64)+"!!"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		near, err := evaluator.Evaluate(mustParse(t, strconv.FormatFloat(v+1e-9, 'f', -1, 64)+"!!"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(exact-near) > 1e-6*exact {
+			t.Errorf("%v!!: expected %v to be close to %v", v, near, exact)
+		}
+	}
+}
+
+// TestGradientFactorial checks the derivatives of the factorials against
+// central finite differences.
+func TestGradientFactorial(t *testing.T) {
+	expressions := []string{"x!", "x!!", "(x * y)! + y", "(x + 1)!! / x"}
+	points := [][2]float64{{0.5, 1.5}, {1.3, 0.2}, {2.7, 3}, {4, 0.5}, {3, 1}}
+
+	for i, s := range expressions {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n := mustParse(t, s)
+
+			f := func(x, y float64) float64 {
+				v, err := evaluator.Evaluate(n, map[string]float64{"x": x, "y": y})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return v
+			}
+
+			for _, p := range points {
+				_, gradient, err := evaluator.Gradient(n, map[string]float64{"x": p[0], "y": p[1]}, []string{"x", "y"})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				h := 1e-6
+				expected := []float64{
+					(f(p[0]+h, p[1]) - f(p[0]-h, p[1])) / (2 * h),
+					(f(p[0], p[1]+h) - f(p[0], p[1]-h)) / (2 * h),
+				}
+
+				for j := range expected {
+					if math.Abs(gradient[j]-expected[j]) > 1e-5*math.Max(1, math.Abs(expected[j])) {
+						t.Errorf("%v: expected derivative %d to be %v but got %v", p, j, expected[j], gradient[j])
+					}
+				}
+			}
+		})
+	}
+}
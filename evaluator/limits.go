@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"strconv"
+)
+
+// DefaultMaxDepth is the deepest tree which can be evaluated with the default
+// environment, or an environment from NewEnv.
+const DefaultMaxDepth = 10000
+
+// DefaultMaxOperations is the most nodes which can be evaluated with the
+// default environment, or an environment from NewEnv. It's enough for any tree
+// within the parser's default limits.
+const DefaultMaxOperations = 1000000
+
+// Limit is a limit which can be exceeded while evaluating. Limits are errors
+// themselves so that errors can be matched by limit using errors.Is, such as
+// errors.Is(err, evaluator.DepthLimit).
+type Limit int
+
+const (
+	// DepthLimit is Env.MaxDepth.
+	DepthLimit Limit = iota + 1
+
+	// OperationLimit is Env.MaxOperations.
+	OperationLimit
+
+	// ContextLimit is the context passed to EvaluateContext being cancelled
+	// or passing its deadline.
+	ContextLimit
+)
+
+var limitNames = map[Limit]string{
+	DepthLimit:     "depth limit",
+	OperationLimit: "operation limit",
+	ContextLimit:   "context limit",
+}
+
+func (l Limit) Error() string {
+	if name, ok := limitNames[l]; ok {
+		return name
+	}
+
+	return "unknown limit " + strconv.Itoa(int(l))
+}
+
+// LimitError is returned when evaluating exceeds one of its limits.
+type LimitError struct {
+	Limit Limit
+	Max   int
+
+	// Pos is the position of Node, the node being evaluated when the limit
+	// was exceeded.
+	Pos  int
+	Node ast.Node
+
+	// Err is the context's error for ContextLimit, such as
+	// context.DeadlineExceeded.
+	Err error
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case DepthLimit:
+		return fmt.Sprintf("exceeded maximum depth of %d at %d", e.Max, e.Pos)
+	case OperationLimit:
+		return fmt.Sprintf("exceeded maximum of %d operations at %d", e.Max, e.Pos)
+	case ContextLimit:
+		return fmt.Sprintf("evaluation stopped at %d: %s", e.Pos, e.Err)
+	default:
+		return fmt.Sprintf("exceeded %s of %d at %d", e.Limit, e.Max, e.Pos)
+	}
+}
+
+// Is returns whether target is the limit which e exceeded.
+func (e *LimitError) Is(target error) bool {
+	l, ok := target.(Limit)
+
+	return ok && l == e.Limit
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// checkDepth returns a LimitError if n, which is depth levels into the tree,
+// is deeper than max. A max of zero means that there is no limit.
+func checkDepth(n ast.Node, depth, max int) error {
+	if max > 0 && depth > max {
+		return &LimitError{Limit: DepthLimit, Max: max, Pos: n.Pos(), Node: n}
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package evaluator_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/token"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chain returns a tree of n additions of 1, nested down the right hand side.
+func chain(n int) ast.Node {
+	var node ast.Node = ast.Lit{Type: token.NumberToken, Value: "1", ValuePos: 2 * n}
+
+	for i := n - 1; i >= 0; i-- {
+		left := ast.Lit{Type: token.NumberToken, Value: "1", ValuePos: 2 * i}
+		node = ast.BinaryExpr{Left: left, Right: node, Op: "+", OpPos: 2*i + 1}
+	}
+
+	return node
+}
+
+// balanced returns a tree of additions of 1 which is depth levels deep on
+// every side.
+func balanced(depth int) ast.Node {
+	if depth == 1 {
+		return ast.Lit{Type: token.NumberToken, Value: "1"}
+	}
+
+	return ast.BinaryExpr{Left: balanced(depth - 1), Right: balanced(depth - 1), Op: "+"}
+}
+
+func TestDepthLimit(t *testing.T) {
+	unlimited := evaluator.NewEnv(nil)
+	unlimited.MaxDepth = 0
+
+	small := evaluator.NewEnv(nil)
+	small.MaxDepth = 3
+
+	cases := []struct {
+		n        ast.Node
+		env      evaluator.Env
+		expected float64
+		pos      int
+	}{
+		{chain(1000000), evaluator.NewEnv(nil), 0, 2*evaluator.DefaultMaxDepth - 2},
+		{chain(evaluator.DefaultMaxDepth - 1), evaluator.NewEnv(nil), evaluator.DefaultMaxDepth, 0},
+		{chain(2), small, 3, 0},
+		{chain(3), small, 0, 4},
+		{chain(100000), unlimited, 100001, 0},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			actual, err := evaluator.EvaluateEnv(c.n, c.env)
+
+			if c.expected != 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if actual != c.expected {
+					t.Fatalf("expected %v but got %v", c.expected, actual)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, evaluator.DepthLimit) {
+				t.Fatalf("expected depth limit error, got %v", err)
+			}
+
+			var l *evaluator.LimitError
+			if !errors.As(err, &l) {
+				t.Fatalf("expected *evaluator.LimitError, got %T", err)
+			}
+
+			if l.Pos != c.pos {
+				t.Fatalf("expected error at %d, got %d", c.pos, l.Pos)
+			}
+		})
+	}
+}
+
+func TestEvaluateDepthLimit(t *testing.T) {
+	_, err := evaluator.Evaluate(chain(1000000), nil)
+
+	if !errors.Is(err, evaluator.DepthLimit) {
+		t.Fatalf("expected depth limit error, got %v", err)
+	}
+
+	_, _, err = evaluator.Gradient(chain(1000000), nil, nil)
+
+	if !errors.Is(err, evaluator.DepthLimit) {
+		t.Fatalf("expected depth limit error from Gradient, got %v", err)
+	}
+
+	_, err = evaluator.PartialEval(chain(1000000), nil)
+
+	if !errors.Is(err, evaluator.DepthLimit) {
+		t.Fatalf("expected depth limit error from PartialEval, got %v", err)
+	}
+}
+
+func TestParsedDepth(t *testing.T) {
+	// Every tree which the parser accepts with its default limits should be
+	// deep enough to evaluate.
+	max := parser.DefaultLimits.MaxDepth
+
+	cases := []struct {
+		s        string
+		expected float64
+	}{
+		{strings.Repeat("1 + ", max-1) + "1", float64(max)},
+		{strings.Repeat("1 ^ ", max-1) + "1", 1},
+		{"0" + strings.Repeat(" !", max-1), 1},
+		{strings.Repeat("(", max-1) + "x" + strings.Repeat(")", max-1), 2},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			constants := map[string]float64{"x": 2}
+
+			actual, err := evaluator.Evaluate(n, constants)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != c.expected {
+				t.Fatalf("expected %v but got %v", c.expected, actual)
+			}
+
+			if _, _, err := evaluator.Gradient(n, constants, []string{"x"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := evaluator.PartialEval(n, nil); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestOperationLimit(t *testing.T) {
+	cases := []struct {
+		n        ast.Node
+		max      int
+		expected float64
+		pos      int
+	}{
+		{chain(2), 5, 3, 0},
+		{chain(2), 4, 0, 4},
+		{chain(100000), 1000, 0, 1000},
+		{mustParse(t, "2 ^ 3!"), 4, 64, 0},
+		{mustParse(t, "2 ^ 3!"), 3, 0, 4},
+		{chain(5000), 0, 5001, 0},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			env := evaluator.NewEnv(nil)
+			env.MaxOperations = c.max
+
+			actual, err := evaluator.EvaluateEnv(c.n, env)
+
+			if c.expected != 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if actual != c.expected {
+					t.Fatalf("expected %v but got %v", c.expected, actual)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, evaluator.OperationLimit) {
+				t.Fatalf("expected operation limit error, got %v", err)
+			}
+
+			var l *evaluator.LimitError
+			if !errors.As(err, &l) {
+				t.Fatalf("expected *evaluator.LimitError, got %T", err)
+			}
+
+			if l.Pos != c.pos {
+				t.Fatalf("expected error at %d, got %d", c.pos, l.Pos)
+			}
+		})
+	}
+}
+
+func TestDefaultOperationLimit(t *testing.T) {
+	if max := evaluator.NewEnv(nil).MaxOperations; max != evaluator.DefaultMaxOperations {
+		t.Fatalf("expected a limit of %d operations, got %d", evaluator.DefaultMaxOperations, max)
+	}
+
+	// 2^20 additions take more than a million operations.
+	_, err := evaluator.Evaluate(balanced(21), nil)
+
+	if !errors.Is(err, evaluator.OperationLimit) {
+		t.Fatalf("expected operation limit error, got %v", err)
+	}
+
+	actual, err := evaluator.Evaluate(balanced(19), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual != 1<<18 {
+		t.Fatalf("expected %v but got %v", 1<<18, actual)
+	}
+}
+
+func TestEvaluateContext(t *testing.T) {
+	background := func() (context.Context, context.CancelFunc) {
+		return context.WithCancel(context.Background())
+	}
+
+	cancelled := func() (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		return ctx, cancel
+	}
+
+	expired := func() (context.Context, context.CancelFunc) {
+		return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	}
+
+	cases := []struct {
+		ctx      func() (context.Context, context.CancelFunc)
+		s        string
+		expected float64
+		err      error
+		pos      int
+	}{
+		{background, "1 + 2 ^ 3", 9, nil, 0},
+		{cancelled, "1 + 2", 0, context.Canceled, 0},
+		{expired, "1 + 2", 0, context.DeadlineExceeded, 0},
+		{background, "1 + 2 * 3", 7, nil, 0},
+		{background, "2 * 3 + 4", 0, context.Canceled, 8},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			ctx, cancel := c.ctx()
+			defer cancel()
+
+			// Cancel part way through, as a slow operator might.
+			env := evaluator.NewEnv(nil)
+			env.Binary["*"] = func(a, b float64) float64 {
+				cancel()
+				return a * b
+			}
+
+			actual, err := evaluator.EvaluateContext(ctx, mustParse(t, c.s), env)
+
+			if c.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if actual != c.expected {
+					t.Fatalf("expected %v but got %v", c.expected, actual)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, evaluator.ContextLimit) || !errors.Is(err, c.err) {
+				t.Fatalf("expected %v context limit error, got %v", c.err, err)
+			}
+
+			var l *evaluator.LimitError
+			if !errors.As(err, &l) {
+				t.Fatalf("expected *evaluator.LimitError, got %T", err)
+			}
+
+			if l.Pos != c.pos {
+				t.Fatalf("expected error at %d, got %d", c.pos, l.Pos)
+			}
+		})
+	}
+}
+
+// TestGradientAndPartialEvalLimits checks that Gradient and PartialEval share
+// the operation limit and context of EvaluateContext.
+func TestGradientAndPartialEvalLimits(t *testing.T) {
+	gradient := func(ctx context.Context, n ast.Node, env evaluator.Env) error {
+		_, _, err := evaluator.GradientContext(ctx, n, env, []string{"x"})
+		return err
+	}
+
+	partial := func(ctx context.Context, n ast.Node, env evaluator.Env) error {
+		_, err := evaluator.PartialEvalContext(ctx, n, env)
+		return err
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		f   func(context.Context, ast.Node, evaluator.Env) error
+		ctx context.Context
+		max int
+		err error
+	}{
+		{gradient, context.Background(), 5, nil},
+		{gradient, context.Background(), 4, evaluator.OperationLimit},
+		{gradient, cancelled, 5, context.Canceled},
+		{partial, context.Background(), 5, nil},
+		{partial, context.Background(), 4, evaluator.OperationLimit},
+		{partial, cancelled, 5, context.Canceled},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			env := evaluator.NewEnv(map[string]float64{"x": 2})
+			env.MaxOperations = c.max
+
+			err := c.f(c.ctx, mustParse(t, "1 + 2 * x"), env)
+
+			if c.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected %v error, got %v", c.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package evaluator
+
+import (
+	"context"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+	"math"
+)
+
+// partial is the result of partially evaluating a node. If known is true then
+// v holds its value, otherwise n holds the residual node.
+type partial struct {
+	n     ast.Node
+	v     float64
+	known bool
+}
+
+// node returns p as a node at the position of original. Values which can't
+// be written as a literal are written as divisions by zero instead, as long as
+// that's what division by zero gives in env.
+func (p partial) node(original ast.Node, env Env) (ast.Node, error) {
+	if !p.known {
+		return p.n, nil
+	}
+
+	pos := original.Pos()
+
+	if l, ok := ast.NewNumberAt(p.v, pos); ok {
+		return l, nil
+	}
+
+	// Dividing by zero gives the same infinity, or NaN for 0 / 0.
+	numerator := 0.0
+
+	if math.IsInf(p.v, 1) {
+		numerator = 1
+	} else if math.IsInf(p.v, -1) {
+		numerator = -1
+	}
+
+	left, _ := ast.NewNumberAt(numerator, pos)
+	right, _ := ast.NewNumberAt(0, pos)
+
+	b := ast.BinaryExpr{Left: left, Right: right, Op: "/", OpPos: pos}
+
+	// Other dialects might not divide with "/".
+	if f, ok := env.Binary["/"]; !ok || !same(f(numerator, 0), p.v) {
+		return nil, &Error{Kind: UnsupportedOperation, Pos: pos, Node: b}
+	}
+
+	return b, nil
+}
+
+// same returns whether a and b are equal, treating NaN as equal to itself.
+func same(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+// partialEval partially evaluates n, which is depth levels into the tree.
+func (e *evaluation) partialEval(n ast.Node, depth int) (partial, error) {
+	env := e.env
+
+	if err := e.enter(n, depth); err != nil {
+		return partial{}, err
+	}
+
+	switch n := n.(type) {
+	case ast.BinaryExpr:
+		left, err := e.partialEval(n.Left, depth+1)
+		if err != nil {
+			return partial{}, err
+		}
+
+		right, err := e.partialEval(n.Right, depth+1)
+		if err != nil {
+			return partial{}, err
+		}
+
+		if left.known && right.known {
+			v, err := env.op(left.v, right.v, n)
+			if err != nil {
+				return partial{}, err
+			}
+
+			return partial{n: n, v: v, known: true}, nil
+		}
+
+		if n.Left, err = left.node(n.Left, env); err != nil {
+			return partial{}, err
+		}
+
+		if n.Right, err = right.node(n.Right, env); err != nil {
+			return partial{}, err
+		}
+
+		return partial{n: n}, nil
+	case ast.UnaryExpr:
+		x, err := e.partialEval(n.X, depth+1)
+		if err != nil {
+			return partial{}, err
+		}
+
+		if x.known {
+			v, err := env.unary(x.v, n)
+			if err != nil {
+				return partial{}, err
+			}
+
+			return partial{n: n, v: v, known: true}, nil
+		}
+
+		if n.X, err = x.node(n.X, env); err != nil {
+			return partial{}, err
+		}
+
+		return partial{n: n}, nil
+	case ast.Lit:
+		// Leave unknown constants for later.
+		if _, ok := env.Constants[n.Unsigned()]; !ok && n.Type == token.ConstantToken {
+			return partial{n: n}, nil
+		}
+
+		v, err := literal(n, env.Constants)
+		if err != nil {
+			return partial{}, err
+		}
+
+		return partial{n: n, v: v, known: true}, nil
+	case ast.BadExpr:
+		return partial{}, &Error{Kind: BadExpression, Pos: n.From, Node: n}
+	default:
+		return partial{}, &Error{Kind: UnknownNode, Node: n}
+	}
+}
+
+// PartialEval evaluates every sub-tree of n which only depends on the provided
+// constants, and returns the residual tree. The residual tree refers to the
+// remaining unknown constants, and evaluating it with them gives the same
+// result as evaluating n with every constant.
+//
+// Values which can't be written as a literal are written as divisions by
+// zero, so infinities become "1 / 0" or "-1 / 0" and NaN becomes "0 / 0".
+// Trees deeper than DefaultMaxDepth, or with more than DefaultMaxOperations
+// nodes, give a LimitError.
+func PartialEval(n ast.Node, constants map[string]float64) (ast.Node, error) {
+	env := defaultEnv
+	env.Constants = constants
+
+	return PartialEvalEnv(n, env)
+}
+
+// PartialEvalEnv partially evaluates n like PartialEval, but with the
+// constants, operators and limits in env. Values which can't be written as a
+// literal give an UnsupportedOperation error unless env's "/" gives them when
+// dividing by zero.
+func PartialEvalEnv(n ast.Node, env Env) (ast.Node, error) {
+	return PartialEvalContext(context.Background(), n, env)
+}
+
+// PartialEvalContext partially evaluates n like PartialEvalEnv, stopping with a
+// LimitError if ctx is done before evaluation finishes.
+func PartialEvalContext(ctx context.Context, n ast.Node, env Env) (ast.Node, error) {
+	e := evaluation{ctx: ctx, env: env}
+
+	p, err := e.partialEval(n, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.node(n, env)
+}
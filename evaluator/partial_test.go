@@ -0,0 +1,124 @@
+package evaluator_test
+
+import (
+	"errors"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestPartialEval(t *testing.T) {
+	known := map[string]float64{"a": 2, "b": 3, "Inf": math.Inf(1)}
+
+	cases := []struct {
+		s, expected string
+	}{
+		{"1 + 2", "3"},
+		{"a * b", "6"},
+		{"-a", "-2"},
+		{"x", "x"},
+		{"a * b + x", "(6 + x)"},
+		{"x * (a + 1) - -b ^ 2", "((x * 3) - 9)"},
+		{"(x + a) * (y + b)", "((x + 2) * (y + 3))"},
+		{"x ^ (a / 0)", "(x ^ (1 / 0))"},
+		{"x - Inf", "(x - (1 / 0))"},
+		{"x * -Inf", "(x * (-1 / 0))"},
+		{"y + (Inf - Inf)", "(y + (0 / 0))"},
+		{"a ^ b ^ x", "(2 ^ (3 ^ x))"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			residual, err := evaluator.PartialEval(n, known)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if residual.String() != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, residual)
+			}
+
+			// The residual should give the same result from only the rest of
+			// the constants.
+			unknown := map[string]float64{"x": 1.5, "y": -4}
+			all := map[string]float64{}
+			for _, m := range []map[string]float64{known, unknown} {
+				for k, v := range m {
+					all[k] = v
+				}
+			}
+
+			expected, err := evaluator.Evaluate(n, all)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := evaluator.Evaluate(residual, unknown)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// NaN isn't equal to itself, so compare them separately.
+			if expected != actual && !(math.IsNaN(expected) && math.IsNaN(actual)) {
+				t.Fatalf("expected %v but got %v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestPartialEvalEnv(t *testing.T) {
+	o := parser.DefaultOptions()
+	o.Binary["^"] = parser.Operator{Precedence: 1, Associativity: parser.LeftAssociative}
+
+	env := evaluator.NewEnv(map[string]float64{"a": 5, "b": 3})
+	env.Binary["^"] = func(a, b float64) float64 { return float64(int64(a) ^ int64(b)) }
+
+	cases := []struct {
+		s, expected string
+	}{
+		{"a ^ b", "6"},
+		{"x ^ a ^ b", "((x ^ 5) ^ 3)"},
+		{"x * (a ^ b)", "(x * 6)"},
+		{"x + a / 0", "(x + (1 / 0))"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := o.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			residual, err := evaluator.PartialEvalEnv(n, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if residual.String() != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, residual)
+			}
+		})
+	}
+
+	// Without a "/" which divides by zero, infinities can't be written out.
+	delete(env.Binary, "/")
+	env.Binary["÷"] = func(a, b float64) float64 { return a / b }
+	o.Binary["÷"] = o.Binary["/"]
+
+	n, err := o.ParseString("x + a ÷ 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var e *evaluator.Error
+	if _, err := evaluator.PartialEvalEnv(n, env); !errors.As(err, &e) || e.Kind != evaluator.UnsupportedOperation {
+		t.Fatalf("expected an unsupported operation but got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackwilsdon/go-calc/token"
+	"io"
+	"strconv"
+)
+
+// Kind is the kind of problem described by an Error. Kinds are errors
+// themselves so that errors can be matched by kind using errors.Is, such as
+// errors.Is(err, parser.UnexpectedEOF).
+type Kind int
+
+const (
+	// UnexpectedEOF is running out of tokens while expecting something.
+	UnexpectedEOF Kind = iota + 1
+
+	// UnexpectedToken is finding a token while expecting something else.
+	UnexpectedToken
+
+	// UnknownOperator is an operator which the parser doesn't support.
+	UnknownOperator
+
+	// TrailingToken is a token after the end of an expression.
+	TrailingToken
+)
+
+var kindNames = map[Kind]string{
+	UnexpectedEOF:   "unexpected EOF",
+	UnexpectedToken: "unexpected token",
+	UnknownOperator: "unknown operator",
+	TrailingToken:   "unexpected trailing token",
+}
+
+func (k Kind) Error() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+
+	return "unknown error kind " + strconv.Itoa(int(k))
+}
+
+// Error is a problem found while parsing, such as an unexpected token.
+type Error struct {
+	Kind Kind
+
+	// Pos is the position of the problem in the source. For UnexpectedEOF
+	// this is the position after the last token.
+	Pos int
+
+	// Token is the offending token, and is empty for UnexpectedEOF.
+	Token token.Token
+
+	// Expected describes what was expected instead of Token, such as
+	// "a factor" or "closing parenthesis", if anything.
+	Expected string
+}
+
+func (e *Error) Error() string {
+	switch e.Kind {
+	case UnexpectedEOF:
+		return "unexpected EOF, expected " + e.Expected
+	case UnexpectedToken:
+		return fmt.Sprintf("unexpected %s, expected %s at %d", e.Token, e.Expected, e.Pos)
+	case UnknownOperator:
+		return fmt.Sprintf("unknown operator %s at %d", e.Token, e.Pos)
+	case TrailingToken:
+		return fmt.Sprintf("unexpected trailing %s at %d", e.Token, e.Pos)
+	default:
+		return fmt.Sprintf("%s at %d", e.Kind, e.Pos)
+	}
+}
+
+// Is returns whether target is the kind of e. Errors of kind UnexpectedEOF
+// are also io.ErrUnexpectedEOF.
+func (e *Error) Is(target error) bool {
+	if target == io.ErrUnexpectedEOF {
+		return e.Kind == UnexpectedEOF
+	}
+
+	k, ok := target.(Kind)
+
+	return ok && k == e.Kind
+}
+
+// ErrorList is a list of errors, in the order in which they were found.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Is returns whether any of the errors in the list is target, so that
+// errors.Is(err, parser.UnexpectedToken) works on the list as a whole.
+func (l ErrorList) Is(target error) bool {
+	for _, e := range l {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first error in the list which matches target, so that
+// errors.As can find a *Error in the list as a whole.
+func (l ErrorList) As(target interface{}) bool {
+	for _, e := range l {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Err returns an error equivalent to the list, or nil if it is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	return l
+}
+
+// The helpers below build the errors shared by each of the parsers, so that
+// they all report problems in the same way.
+
+// unexpectedEOF returns an error for running out of tokens at pos while
+// expecting something.
+func unexpectedEOF(pos int, expected string) *Error {
+	return &Error{Kind: UnexpectedEOF, Pos: pos, Expected: expected}
+}
+
+// unexpected returns an error for finding t while expecting something else.
+func unexpected(t token.Token, expected string) *Error {
+	return &Error{Kind: UnexpectedToken, Pos: t.Position, Token: t, Expected: expected}
+}
+
+// unknownOperator returns an error for an operator which isn't supported.
+func unknownOperator(t token.Token) *Error {
+	return &Error{Kind: UnknownOperator, Pos: t.Position, Token: t}
+}
+
+// trailing returns an error for finding t after the end of an expression.
+func trailing(t token.Token) *Error {
+	return &Error{Kind: TrailingToken, Pos: t.Position, Token: t}
+}
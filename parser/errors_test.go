@@ -0,0 +1,132 @@
+package parser_test
+
+import (
+	"errors"
+	"github.com/jackwilsdon/go-calc/parser"
+	"io"
+	"strconv"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	cases := []struct {
+		s        string
+		parse    func(string) (interface{}, error)
+		kind     parser.Kind
+		pos      int
+		token    string
+		expected string
+		err      string
+	}{
+		{"1 +", parseInfix, parser.UnexpectedEOF, 3, "", "a factor", "unexpected EOF, expected a factor"},
+		{"1 + )", parseInfix, parser.UnexpectedToken, 4, ")", "a factor", `unexpected ")", expected a factor at 4`},
+		{"(1 2", parseInfix, parser.UnexpectedToken, 3, "2", "closing parenthesis", `unexpected "2", expected closing parenthesis at 3`},
+		{"1 2", parseInfix, parser.TrailingToken, 2, "2", "", `unexpected trailing "2" at 2`},
+		{"1 +", parseRPN, parser.UnexpectedToken, 2, "+", "two operands", `unexpected "+", expected two operands at 2`},
+		{"(+ 1", parseSExpr, parser.UnexpectedEOF, 4, "", "closing parenthesis", "unexpected EOF, expected closing parenthesis"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, err := c.parse(c.s)
+
+			var e *parser.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected a *parser.Error but got %T (%v)", err, err)
+			}
+
+			if e.Kind != c.kind {
+				t.Errorf("expected kind %q but got %q", c.kind, e.Kind)
+			}
+
+			if !errors.Is(err, c.kind) {
+				t.Errorf("expected error to be %q", c.kind)
+			}
+
+			if e.Pos != c.pos {
+				t.Errorf("expected position %d but got %d", c.pos, e.Pos)
+			}
+
+			if e.Token.Value != c.token {
+				t.Errorf("expected token %q but got %q", c.token, e.Token.Value)
+			}
+
+			if e.Expected != c.expected {
+				t.Errorf("expected %q to be expected but got %q", c.expected, e.Expected)
+			}
+
+			if err.Error() != c.err {
+				t.Errorf("expected error %q but got %q", c.err, err)
+			}
+
+			if errors.Is(err, io.ErrUnexpectedEOF) != (c.kind == parser.UnexpectedEOF) {
+				t.Errorf("expected io.ErrUnexpectedEOF to match only unexpected EOFs")
+			}
+		})
+	}
+}
+
+func TestErrorListIs(t *testing.T) {
+	_, err := parser.ParseRecoverString("1 2 + )")
+
+	var list parser.ErrorList
+	if !errors.As(err, &list) || len(list) != 2 {
+		t.Fatalf("expected two errors but got %v", err)
+	}
+
+	if !errors.Is(list[0], parser.TrailingToken) || !errors.Is(list[1], parser.UnexpectedToken) {
+		t.Fatalf("expected trailing and unexpected tokens but got %v", list)
+	}
+
+	if errors.Is(list[0], parser.UnexpectedEOF) {
+		t.Fatalf("expected %v not to be an unexpected EOF", list[0])
+	}
+}
+
+func TestErrorListMatching(t *testing.T) {
+	_, err := parser.ParseRecoverString("1 + ) 2 3")
+
+	if _, ok := err.(parser.ErrorList); !ok {
+		t.Fatalf("expected an error list but got %T", err)
+	}
+
+	var e *parser.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected to find a *parser.Error in %v", err)
+	}
+
+	if e.Kind != parser.UnexpectedToken || e.Pos != 4 {
+		t.Errorf("expected the first error but got %v", e)
+	}
+
+	if !errors.Is(err, parser.UnexpectedToken) || !errors.Is(err, parser.TrailingToken) {
+		t.Errorf("expected %v to match every kind in the list", err)
+	}
+
+	if errors.Is(err, parser.UnexpectedEOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected %v not to be an unexpected EOF", err)
+	}
+
+	var limit *parser.LimitError
+	if errors.As(err, &limit) {
+		t.Errorf("expected %v not to contain a limit error", err)
+	}
+
+	_, err = parser.ParseRecoverString("(1 +")
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected %v to be an unexpected EOF", err)
+	}
+}
+
+func parseInfix(s string) (interface{}, error) {
+	return parser.ParseString(s)
+}
+
+func parseRPN(s string) (interface{}, error) {
+	return parser.ParseRPNString(s)
+}
+
+func parseSExpr(s string) (interface{}, error) {
+	return parser.ParseSExprString(s)
+}
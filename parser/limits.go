@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"io"
+	"strconv"
+)
+
+// Limits bounds the work done while parsing, to protect against hostile input.
+// A limit of zero means that there is no limit.
+type Limits struct {
+	// MaxDepth is the deepest that a parsed tree can be, counting both sides
+	// of each operator, and the deepest that parsing can nest, such as
+	// through parentheses. Options.ShuntingYard doesn't recurse, so only the
+	// depth of the tree applies to it.
+	MaxDepth int
+
+	// MaxTokens is the most tokens that can be read.
+	MaxTokens int
+
+	// MaxInputSize is the most bytes that can be read. It only applies when
+	// the parser creates the scanner, such as in ParseReader and ParseString.
+	MaxInputSize int
+}
+
+// DefaultLimits holds the limits used by DefaultOptions.
+var DefaultLimits = Limits{
+	MaxDepth:     1000,
+	MaxTokens:    100000,
+	MaxInputSize: 1 << 20,
+}
+
+// Limit is a limit which can be exceeded while parsing. Limits are errors
+// themselves so that errors can be matched by limit using errors.Is, such as
+// errors.Is(err, parser.DepthLimit).
+type Limit int
+
+const (
+	// DepthLimit is Limits.MaxDepth.
+	DepthLimit Limit = iota + 1
+
+	// TokenLimit is Limits.MaxTokens.
+	TokenLimit
+
+	// InputSizeLimit is Limits.MaxInputSize.
+	InputSizeLimit
+)
+
+var limitNames = map[Limit]string{
+	DepthLimit:     "depth limit",
+	TokenLimit:     "token limit",
+	InputSizeLimit: "input size limit",
+}
+
+func (l Limit) Error() string {
+	if name, ok := limitNames[l]; ok {
+		return name
+	}
+
+	return "unknown limit " + strconv.Itoa(int(l))
+}
+
+// LimitError is returned when parsing exceeds one of its limits.
+type LimitError struct {
+	Limit Limit
+	Max   int
+
+	// Pos is the position at which the limit was exceeded, or -1 if it isn't
+	// known.
+	Pos int
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case DepthLimit:
+		return fmt.Sprintf("exceeded maximum depth of %d at %d", e.Max, e.Pos)
+	case TokenLimit:
+		return fmt.Sprintf("exceeded maximum of %d tokens at %d", e.Max, e.Pos)
+	case InputSizeLimit:
+		return fmt.Sprintf("exceeded maximum input size of %d bytes", e.Max)
+	default:
+		return fmt.Sprintf("exceeded %s of %d", e.Limit, e.Max)
+	}
+}
+
+// Is returns whether target is the limit which e exceeded.
+func (e *LimitError) Is(target error) bool {
+	l, ok := target.(Limit)
+
+	return ok && l == e.Limit
+}
+
+// enter records that parsing has gone one level deeper at pos. Each call
+// must be followed by a call to leave.
+func (p *parser) enter(pos int) error {
+	p.depth++
+
+	if max := p.options.Limits.MaxDepth; max > 0 && p.depth > max {
+		return &LimitError{Limit: DepthLimit, Max: max, Pos: pos}
+	}
+
+	return nil
+}
+
+// leave records that parsing has come back up a level.
+func (p *parser) leave() {
+	p.depth--
+}
+
+// checkDepth returns a LimitError at the first node of n which is deeper than
+// the maximum depth. Trees are walked without recursion, as left hand sides
+// can be deeper than parsing nested.
+func (p *parser) checkDepth(n ast.Node) error {
+	max := p.options.Limits.MaxDepth
+	if max <= 0 {
+		return nil
+	}
+
+	type level struct {
+		n     ast.Node
+		depth int
+	}
+
+	stack := []level{{n, 1}}
+
+	for len(stack) > 0 {
+		l := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if l.depth > max {
+			return &LimitError{Limit: DepthLimit, Max: max, Pos: l.n.Pos()}
+		}
+
+		switch n := l.n.(type) {
+		case ast.BinaryExpr:
+			stack = append(stack, level{n.Right, l.depth + 1}, level{n.Left, l.depth + 1})
+		case ast.UnaryExpr:
+			stack = append(stack, level{n.X, l.depth + 1})
+		}
+	}
+
+	return nil
+}
+
+// sizeLimitedReader reads from r, returning a LimitError once more than max
+// bytes have been read.
+type sizeLimitedReader struct {
+	r         io.Reader
+	max       int
+	remaining int
+}
+
+func (l *sizeLimitedReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	// Check that there really is more input before complaining about it.
+	if l.remaining <= 0 {
+		n, err := l.r.Read(b[:1])
+		if n > 0 {
+			return 0, &LimitError{Limit: InputSizeLimit, Max: l.max, Pos: -1}
+		}
+
+		return 0, err
+	}
+
+	if len(b) > l.remaining {
+		b = b[:l.remaining]
+	}
+
+	n, err := l.r.Read(b)
+	l.remaining -= n
+
+	return n, err
+}
+
+// limitReader returns r limited to the maximum input size in o.
+func (o Options) limitReader(r io.Reader) io.Reader {
+	if o.Limits.MaxInputSize <= 0 {
+		return r
+	}
+
+	return &sizeLimitedReader{r: r, max: o.Limits.MaxInputSize, remaining: o.Limits.MaxInputSize}
+}
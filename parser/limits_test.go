@@ -0,0 +1,126 @@
+package parser_test
+
+import (
+	"errors"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	deep := func(o parser.Options, s string) (ast.Node, error) {
+		return o.ParseString(s)
+	}
+
+	recovering := func(o parser.Options, s string) (ast.Node, error) {
+		return o.ParseRecoverString(s)
+	}
+
+	rpn := func(o parser.Options, s string) (ast.Node, error) {
+		return o.ParseRPNString(s)
+	}
+
+	sexpr := func(o parser.Options, s string) (ast.Node, error) {
+		return o.ParseSExprString(s)
+	}
+
+	small := parser.DefaultOptions()
+	small.Limits = parser.Limits{MaxDepth: 3, MaxTokens: 5, MaxInputSize: 8}
+
+	// S-expressions need more tokens than small allows to get deep.
+	shallow := parser.DefaultOptions()
+	shallow.Limits.MaxDepth = 3
+
+	tilde := parser.DefaultOptions()
+	tilde.Prefix["~"] = parser.Operator{Precedence: 4, Associativity: parser.RightAssociative}
+
+	cases := []struct {
+		parse func(o parser.Options, s string) (ast.Node, error)
+		o     parser.Options
+		s     string
+		limit parser.Limit
+		pos   int
+	}{
+		{deep, parser.DefaultOptions(), strings.Repeat("(", 1000000), parser.DepthLimit, 1000},
+		{deep, parser.DefaultOptions(), strings.Repeat("(", 999) + "1" + strings.Repeat(")", 999), 0, 0},
+		{deep, parser.DefaultOptions(), strings.Repeat("2 ^ ", 100000) + "2", parser.DepthLimit, 3999},
+		{deep, tilde, strings.Repeat("~", 100000) + "x", parser.DepthLimit, 1000},
+		{deep, parser.DefaultOptions(), strings.Repeat("1 + ", 60000) + "1", parser.TokenLimit, 200000},
+		{deep, parser.DefaultOptions(), strings.Repeat("1 + ", 20000) + "1", parser.DepthLimit, 0},
+		{deep, parser.DefaultOptions(), strings.Repeat("1 + ", 999) + "1", 0, 0},
+		{deep, parser.DefaultOptions(), "1" + strings.Repeat(" !", 1000), parser.DepthLimit, 0},
+		{recovering, parser.DefaultOptions(), strings.Repeat("1 + ", 20000) + "1", parser.DepthLimit, 0},
+		{rpn, parser.DefaultOptions(), "1" + strings.Repeat(" 1 +", 1000), parser.DepthLimit, 0},
+		{rpn, parser.DefaultOptions(), "1" + strings.Repeat(" 1 +", 999), 0, 0},
+		{sexpr, parser.DefaultOptions(), "(+" + strings.Repeat(" 1", 1001) + ")", parser.DepthLimit, 3},
+		{deep, parser.DefaultOptions(), strings.Repeat("1", 1<<20+1), parser.InputSizeLimit, -1},
+		{deep, parser.DefaultOptions(), strings.Repeat("1", 1<<20), 0, 0},
+		{recovering, parser.DefaultOptions(), strings.Repeat("(", 1000000), parser.DepthLimit, 1000},
+		{recovering, parser.DefaultOptions(), strings.Repeat("1 ) ", 100000), parser.TokenLimit, 200000},
+		{rpn, parser.DefaultOptions(), strings.Repeat("1 ", 100001), parser.TokenLimit, 200000},
+		{sexpr, parser.DefaultOptions(), strings.Repeat("(+ 1 ", 1000000), parser.DepthLimit, 5000},
+		{deep, small, "((1))", 0, 0},
+		{deep, small, "(((1)))", parser.DepthLimit, 3},
+		{deep, small, "1+2+3+4", parser.TokenLimit, 5},
+		{deep, small, "123456789", parser.InputSizeLimit, -1},
+		{rpn, small, "1 2 +", 0, 0},
+		{rpn, small, "1 ! ! !", parser.DepthLimit, 0},
+		{rpn, small, "1 2+3+4+", parser.TokenLimit, 6},
+		{rpn, small, "1 2 + 3 +", parser.InputSizeLimit, -1},
+		{sexpr, small, "(+ 1 2)", 0, 0},
+		{sexpr, small, "(-(-(-1)))", parser.TokenLimit, 5},
+		{sexpr, small, "(+ 1 2 3)", parser.InputSizeLimit, -1},
+		{sexpr, shallow, "(+ 1 2 3)", 0, 0},
+		{sexpr, shallow, "(+ 1 2 3 4)", parser.DepthLimit, 3},
+		{sexpr, shallow, "(! (! (! (! 1))))", parser.DepthLimit, 9},
+		{deep, parser.Options{Binary: parser.DefaultOptions().Binary}, strings.Repeat("(", 10000) + "1" + strings.Repeat(")", 10000), 0, 0},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, err := c.parse(c.o, c.s)
+
+			if c.limit == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %q", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, c.limit) {
+				t.Fatalf("expected %s error, got %v", c.limit, err)
+			}
+
+			var l *parser.LimitError
+			if !errors.As(err, &l) {
+				t.Fatalf("expected *parser.LimitError, got %T", err)
+			}
+
+			if l.Pos != c.pos {
+				t.Fatalf("expected error at %d, got %d", c.pos, l.Pos)
+			}
+		})
+	}
+}
+
+func TestLimitErrorMessages(t *testing.T) {
+	cases := []struct {
+		err *parser.LimitError
+		s   string
+	}{
+		{&parser.LimitError{Limit: parser.DepthLimit, Max: 3, Pos: 3}, "exceeded maximum depth of 3 at 3"},
+		{&parser.LimitError{Limit: parser.TokenLimit, Max: 5, Pos: 8}, "exceeded maximum of 5 tokens at 8"},
+		{&parser.LimitError{Limit: parser.InputSizeLimit, Max: 8, Pos: -1}, "exceeded maximum input size of 8 bytes"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			if c.err.Error() != c.s {
+				t.Fatalf("expected %q, got %q", c.s, c.err)
+			}
+		})
+	}
+}
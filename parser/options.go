@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/jackwilsdon/go-calc/token"
+	"io"
+	"sort"
+)
+
+// Associativity is the way in which operators of the same precedence group.
+type Associativity int
+
+const (
+	// LeftAssociative operators group to the left, so "1 - 2 - 3" is
+	// "(1 - 2) - 3".
+	LeftAssociative Associativity = iota
+
+	// RightAssociative operators group to the right, so "2 ^ 3 ^ 4" is
+	// "2 ^ (3 ^ 4)".
+	RightAssociative
+)
+
+// Operator describes how tightly an operator binds. Operators with a higher
+// precedence bind tighter, and precedences start from 1. Parsing with an
+// operator of a lower precedence fails.
+type Operator struct {
+	Precedence    int
+	Associativity Associativity
+}
+
+// operandPrecedence returns the minimum precedence of the operators which can
+// appear in the operand after op without parentheses.
+func operandPrecedence(op Operator) int {
+	if op.Associativity == LeftAssociative {
+		return op.Precedence + 1
+	}
+
+	return op.Precedence
+}
+
+// operators holds the default binary operators.
+var operators = map[string]Operator{
+	"+": {1, LeftAssociative},
+	"-": {1, LeftAssociative},
+	"*": {2, LeftAssociative},
+	"/": {2, LeftAssociative},
+	"^": {3, RightAssociative},
+}
+
+// postfixOperators holds the default postfix operators: factorial, double
+// factorial and percent. They bind tighter than any binary operator, so
+// "2 ^ 3!" is "2 ^ (3!)".
+var postfixOperators = map[string]Operator{
+	"!":  {4, LeftAssociative},
+	"!!": {4, LeftAssociative},
+	"%":  {4, LeftAssociative},
+}
+
+// Options holds the operators recognised by the infix parser, and the limits
+// on the work it does.
+//
+// Signs directly before a number or constant, such as "-5", are always part
+// of the literal. If "+" or "-" are prefix operators then signs before
+// anything else, such as "-(1 + 2)", are parsed as them.
+//
+// An operator which is both a postfix and a binary operator is always parsed
+// as a postfix operator. The associativity of prefix and postfix operators
+// only affects how they group with binary operators of the same precedence.
+type Options struct {
+	Binary  map[string]Operator
+	Prefix  map[string]Operator
+	Postfix map[string]Operator
+	Limits  Limits
+
+	// ShuntingYard parses expressions using the shunting-yard algorithm,
+	// which keeps its state in explicit stacks rather than recursing, so that
+	// deeply nested expressions can be parsed. It builds the same trees and
+	// gives the same errors as the default precedence climbing parser, but
+	// isn't used by the ParseRecover functions.
+	ShuntingYard bool
+}
+
+// DefaultOptions returns the options used by ParseScanner and the other
+// package level functions. The maps are new on every call, so operators can be
+// added, removed or overridden in them freely.
+func DefaultOptions() Options {
+	o := Options{
+		Binary:  map[string]Operator{},
+		Prefix:  map[string]Operator{},
+		Postfix: map[string]Operator{},
+		Limits:  DefaultLimits,
+	}
+
+	for k, v := range operators {
+		o.Binary[k] = v
+	}
+
+	for k, v := range postfixOperators {
+		o.Postfix[k] = v
+	}
+
+	return o
+}
+
+var defaultOptions = DefaultOptions()
+
+// validate returns an error if any of the operators in o can't be parsed.
+func (o Options) validate() error {
+	tables := []struct {
+		name string
+		ops  map[string]Operator
+	}{
+		{"binary", o.Binary},
+		{"prefix", o.Prefix},
+		{"postfix", o.Postfix},
+	}
+
+	for _, table := range tables {
+		names := make([]string, 0, len(table.ops))
+
+		for name := range table.ops {
+			names = append(names, name)
+		}
+
+		// Check them in order so that the same error is always reported.
+		sort.Strings(names)
+
+		for _, name := range names {
+			if p := table.ops[name].Precedence; p < 1 {
+				return fmt.Errorf("invalid precedence %d for %s operator %q, precedences start from 1", p, table.name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Operators returns every operator which is scanned when parsing with o: the
+// operators in each of its tables, along with signs and the equals sign.
+func (o Options) Operators() []string {
+	ops := []string{"+", "-", "="}
+
+	for _, m := range []map[string]Operator{o.Binary, o.Prefix, o.Postfix} {
+		for op := range m {
+			ops = append(ops, op)
+		}
+	}
+
+	return ops
+}
+
+// scanner returns a scanner for r which recognises o.Operators(), and stops at
+// the maximum input size.
+func (o Options) scanner(r io.Reader) *token.Scanner {
+	return token.NewOperatorScanner(o.limitReader(r), o.Operators())
+}
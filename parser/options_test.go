@@ -0,0 +1,175 @@
+package parser_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// dialect returns options for a language where "^" is XOR, "**" is power,
+// "~" and "-" are prefix operators and "!" binds tighter than "~".
+func dialect() parser.Options {
+	o := parser.DefaultOptions()
+	o.Binary["^"] = parser.Operator{Precedence: 1, Associativity: parser.LeftAssociative}
+	o.Binary["**"] = parser.Operator{Precedence: 4, Associativity: parser.RightAssociative}
+	o.Binary["+"] = parser.Operator{Precedence: 2, Associativity: parser.LeftAssociative}
+	o.Binary["*"] = parser.Operator{Precedence: 3, Associativity: parser.LeftAssociative}
+	delete(o.Binary, "-")
+	delete(o.Binary, "/")
+	o.Prefix["~"] = parser.Operator{Precedence: 5, Associativity: parser.RightAssociative}
+	o.Prefix["-"] = parser.Operator{Precedence: 3, Associativity: parser.RightAssociative}
+	o.Postfix["!"] = parser.Operator{Precedence: 6}
+	return o
+}
+
+func TestOptions(t *testing.T) {
+	cases := []struct {
+		s, n string
+	}{
+		{"1 ^ 2 + 3", "(1 ^ (2 + 3))"},
+		{"1 + 2 ^ 3", "((1 + 2) ^ 3)"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"2**3*4", "((2 ** 3) * 4)"},
+		{"~x ^ y", "((~x) ^ y)"},
+		{"~~x", "(~(~x))"},
+		{"-x * 2", "(-x * 2)"},
+		{"-(x + 1) * 2", "(-((x + 1) * 2))"},
+		{"-(x) + 1", "((-x) + 1)"},
+		{"--(x) ** 2", "(-(-(x ** 2)))"},
+		{"-~x", "(-(~x))"},
+		{"3! ** 2", "((3!) ** 2)"},
+		{"2 ** 3!", "(2 ** (3!))"},
+		{"~3!", "(~(3!))"},
+		{"(1 + 2)!!", "((1 + 2)!!)"},
+		{"(1 + 2)! !", "(((1 + 2)!)!)"},
+	}
+
+	o := dialect()
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := o.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n.String() != c.n {
+				t.Fatalf("expected %q, got %q", c.n, n)
+			}
+		})
+	}
+}
+
+func TestOptionsErrors(t *testing.T) {
+	cases := []struct {
+		s, err string
+	}{
+		{"1 - 2", `unknown operator "-" at 2`},
+		{"1 / 2", `unexpected trailing "/" at 2`},
+		{"~", "unexpected EOF, expected a factor"},
+		{"1 ~ 2", `unknown operator "~" at 2`},
+		{"!1", `unexpected "!", expected a factor at 0`},
+	}
+
+	o := dialect()
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, err := o.ParseString(c.s)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if err.Error() != c.err {
+				t.Fatalf("expected error %q but got %q", c.err, err)
+			}
+		})
+	}
+}
+
+func TestOptionsPrecedence(t *testing.T) {
+	cases := []struct {
+		table func(o parser.Options) map[string]parser.Operator
+		err   string
+	}{
+		{
+			func(o parser.Options) map[string]parser.Operator { return o.Binary },
+			`invalid precedence 0 for binary operator "&", precedences start from 1`,
+		},
+		{
+			func(o parser.Options) map[string]parser.Operator { return o.Prefix },
+			`invalid precedence 0 for prefix operator "&", precedences start from 1`,
+		},
+		{
+			func(o parser.Options) map[string]parser.Operator { return o.Postfix },
+			`invalid precedence 0 for postfix operator "&", precedences start from 1`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			o := parser.DefaultOptions()
+			c.table(o)["&"] = parser.Operator{}
+
+			_, err := o.ParseString("1 + 2")
+			if err == nil || err.Error() != c.err {
+				t.Errorf("expected error %q but got %v", c.err, err)
+			}
+
+			_, _, err = o.ParseEquationString("1 = 2")
+			if err == nil || err.Error() != c.err {
+				t.Errorf("expected equation error %q but got %v", c.err, err)
+			}
+
+			_, err = o.ParseRecoverString("1 + 2")
+			if err == nil || err.Error() != c.err {
+				t.Errorf("expected recover error %q but got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestOptionsPositions(t *testing.T) {
+	n, err := dialect().ParseString("~x ** 12!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := n.(ast.BinaryExpr).Left.(ast.UnaryExpr)
+	postfix := n.(ast.BinaryExpr).Right.(ast.UnaryExpr)
+
+	if prefix.Pos() != 0 || prefix.End() != 2 {
+		t.Errorf("expected prefix to span 0:2 but got %d:%d", prefix.Pos(), prefix.End())
+	}
+
+	if postfix.Pos() != 6 || postfix.End() != 9 || postfix.OpPos != 8 {
+		t.Errorf("expected postfix to span 6:9 with operator at 8 but got %d:%d at %d", postfix.Pos(), postfix.End(), postfix.OpPos)
+	}
+}
+
+func TestDefaultOptionsCopy(t *testing.T) {
+	o := parser.DefaultOptions()
+	delete(o.Binary, "+")
+
+	if _, err := parser.ParseString("1 + 2"); err != nil {
+		t.Fatalf("expected changes to options not to affect the defaults but got %v", err)
+	}
+}
+
+func TestOptionsOperators(t *testing.T) {
+	o := parser.Options{
+		Binary:  map[string]parser.Operator{"*": {Precedence: 1}},
+		Prefix:  map[string]parser.Operator{"~": {Precedence: 2}},
+		Postfix: map[string]parser.Operator{"!": {Precedence: 3}},
+	}
+
+	ops := o.Operators()
+	sort.Strings(ops)
+
+	if s := strings.Join(ops, " "); s != "! * + - = ~" {
+		t.Fatalf("expected ! * + - = ~ but got %s", s)
+	}
+}
@@ -1,29 +1,13 @@
 package parser
 
 import (
-	"errors"
-	"fmt"
 	"github.com/jackwilsdon/go-calc/ast"
 	"github.com/jackwilsdon/go-calc/token"
 	"io"
 	"strings"
+	"unicode/utf8"
 )
 
-const (
-	leftAssociativity = iota
-	rightAssociativity
-)
-
-var operators = map[string]struct {
-	precedence, associativity int
-}{
-	"+": {1, leftAssociativity},
-	"-": {1, leftAssociativity},
-	"*": {2, leftAssociativity},
-	"/": {2, leftAssociativity},
-	"^": {3, rightAssociativity},
-}
-
 // collapseSigns converts a sequence of signs into a single sign.
 // For example, "+-+-+-" is converted into just "-".
 func collapseSigns(signs string) string {
@@ -46,6 +30,23 @@ func collapseSigns(signs string) string {
 type parser struct {
 	scanner   *token.Scanner
 	nextToken *token.Token
+	options   Options
+
+	// tokens is the number of tokens read so far, and depth is how deeply
+	// nested parsing currently is.
+	tokens int
+	depth  int
+
+	// end is the position after the last token returned by next.
+	end int
+
+	// recovering is set when errors should be collected into errors and
+	// parsing continued, rather than stopping at the first one.
+	recovering bool
+	errors     ErrorList
+
+	// groups is the number of parentheses which are currently open.
+	groups int
 }
 
 // peek returns the next token without advancing past it.
@@ -62,6 +63,12 @@ func (p *parser) peek() (token.Token, error) {
 		return token.Token{}, err
 	}
 
+	p.tokens++
+
+	if max := p.options.Limits.MaxTokens; max > 0 && p.tokens > max {
+		return token.Token{}, &LimitError{Limit: TokenLimit, Max: max, Pos: nextToken.Position}
+	}
+
 	// Store the next token for future peek calls.
 	p.nextToken = &nextToken
 
@@ -70,25 +77,114 @@ func (p *parser) peek() (token.Token, error) {
 
 // next returns the next token and advances past it.
 func (p *parser) next() (token.Token, error) {
-	// If there's no next token then we need to scan for one.
-	if p.nextToken == nil {
-		return p.scanner.Scan()
+	t, err := p.peek()
+	if err != nil {
+		return token.Token{}, err
 	}
 
-	// We already know what the next token is so clear the next token and
-	// return it.
-	nextToken := p.nextToken
 	p.nextToken = nil
-	return *nextToken, nil
+	p.end = t.Position + utf8.RuneCountInString(t.Value)
+
+	return t, nil
+}
+
+// backup makes t, which must be the token last returned by next, the next
+// token again.
+func (p *parser) backup(t token.Token) {
+	p.nextToken = &t
+}
+
+// error returns err, unless the parser is recovering, in which case err is
+// recorded and nil is returned so that parsing can continue.
+func (p *parser) error(err *Error) error {
+	if !p.recovering {
+		return err
+	}
+
+	p.record(err)
+
+	return nil
+}
+
+// record adds err to the list of errors, unless it repeats the last one.
+func (p *parser) record(err *Error) {
+	if n := len(p.errors); n > 0 && p.errors[n-1].Pos == err.Pos && p.errors[n-1].Kind == err.Kind {
+		return
+	}
+
+	p.errors = append(p.errors, err)
+}
+
+// bad reports err and, when recovering, returns a BadExpr from from to to in
+// place of the node which couldn't be parsed.
+func (p *parser) bad(err *Error, from, to int) (ast.Node, error) {
+	if err := p.error(err); err != nil {
+		return nil, err
+	}
+
+	return ast.BadExpr{From: from, To: to}, nil
+}
+
+// synchronising returns whether t is a token which parsing can pick up
+// from again after an error.
+func (p *parser) synchronising(t token.Token) bool {
+	if t.Type == token.ParenthesisToken {
+		return t.Value == ")"
+	}
+
+	_, valid := p.options.Binary[t.Value]
+
+	return t.Type == token.OperatorToken && valid
+}
+
+// skip skips tokens, starting with t which has already been consumed, until
+// the next operator or closing parenthesis which isn't nested inside the
+// skipped tokens.
+func (p *parser) skip(t token.Token) error {
+	var depth int
+
+	for {
+		if t.Type == token.ParenthesisToken && t.Value == "(" {
+			depth++
+		} else if t.Type == token.ParenthesisToken && t.Value == ")" && depth > 0 {
+			depth--
+		}
+
+		next, err := p.peek()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if depth == 0 && p.synchronising(next) {
+			return nil
+		}
+
+		if t, err = p.next(); err != nil {
+			return err
+		}
+	}
 }
 
 func (p *parser) expression(minimumPrecedence int) (ast.Node, error) {
+	// Every level of nesting in the input passes through here.
+	if err := p.enter(p.end); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
 	left, err := p.factor()
 
 	if err != nil {
 		return nil, err
 	}
 
+	return p.binary(left, minimumPrecedence)
+}
+
+// binary parses any operators and right hand sides following left.
+func (p *parser) binary(left ast.Node, minimumPrecedence int) (ast.Node, error) {
 	for {
 		t, err := p.peek()
 
@@ -105,34 +201,64 @@ func (p *parser) expression(minimumPrecedence int) (ast.Node, error) {
 			break
 		}
 
+		// Stop at an equals sign as it separates the sides of an equation,
+		// rather than being an operator.
+		if t.Value == "=" {
+			break
+		}
+
+		// Postfix operators apply to everything before them which binds at
+		// least as tightly.
+		if op, ok := p.options.Postfix[t.Value]; ok {
+			if op.Precedence < minimumPrecedence {
+				break
+			}
+
+			if _, err := p.next(); err != nil {
+				return nil, err
+			}
+
+			left = ast.UnaryExpr{X: left, Op: t.Value, OpPos: t.Position, Postfix: true}
+			continue
+		}
+
 		// Look up some information about the operator.
-		op, valid := operators[t.Value]
+		op, valid := p.options.Binary[t.Value]
 
 		if !valid {
-			return nil, fmt.Errorf("unknown operator %s at %d", t, t.Position)
+			if err := p.error(unknownOperator(t)); err != nil {
+				return nil, err
+			}
+
+			// Skip past the operator and its right hand side, and mark the
+			// whole operation as bad.
+			if _, err := p.next(); err != nil {
+				return nil, err
+			}
+
+			right, err := p.factor()
+
+			if err != nil {
+				return nil, err
+			}
+
+			left = ast.BadExpr{From: left.Pos(), To: right.End()}
+			continue
 		}
 
 		// If this operator has a lower precedence than the minimum then we
 		// don't want to look at it now.
-		if op.precedence < minimumPrecedence {
+		if op.Precedence < minimumPrecedence {
 			break
 		}
 
-		nextMinimumPrecedence := op.precedence
-
-		// If the operator has left associativity then the next minimum
-		// precedence is the precedence of the current operator plus one.
-		if op.associativity == leftAssociativity {
-			nextMinimumPrecedence += 1
-		}
-
 		// Consume the token now that we're sure everything is good.
 		if _, err := p.next(); err != nil {
 			return nil, err
 		}
 
 		// Recursively work out the right hand side of the expression.
-		right, err := p.expression(nextMinimumPrecedence)
+		right, err := p.expression(operandPrecedence(op))
 
 		if err != nil {
 			return nil, err
@@ -140,7 +266,7 @@ func (p *parser) expression(minimumPrecedence int) (ast.Node, error) {
 
 		// Set the left hand side to the newly generated binary expression and
 		// go around again.
-		left = ast.BinaryExpr{Left: left, Right: right, Op: t.Value}
+		left = ast.BinaryExpr{Left: left, Right: right, Op: t.Value, OpPos: t.Position}
 	}
 
 	return left, nil
@@ -149,76 +275,254 @@ func (p *parser) expression(minimumPrecedence int) (ast.Node, error) {
 func (p *parser) factor() (ast.Node, error) {
 	t, err := p.next()
 	if err == io.EOF {
-		return nil, errors.New("unexpected EOF, expected a factor")
+		return p.bad(unexpectedEOF(p.end, "a factor"), p.end, p.end)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Handle unary prefixes.
-	if t.Type == token.OperatorToken && (t.Value == "+" || t.Value == "-") {
-		signs := t.Value
-
-		// Keep consuming operators and adding them onto the sign string.
-		for t.Type == token.OperatorToken && (t.Value == "+" || t.Value == "-") {
-			t, err = p.next()
-			if err == io.EOF {
-				return nil, errors.New("unexpected EOF, expected a number")
-			} else if err != nil {
-				return nil, err
-			}
+	// Closing parentheses which don't close anything are dropped when
+	// recovering, so that each one is only reported once.
+	for p.recovering && p.groups == 0 && t.Type == token.ParenthesisToken && t.Value == ")" {
+		p.record(unexpected(t, "a factor"))
+		from := t.Position
 
-			signs += t.Value
+		if t, err = p.next(); err == io.EOF {
+			return ast.BadExpr{From: from, To: p.end}, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// Handle unary prefixes.
+	if sign(t) {
+		signTokens, t, err := p.signs(t)
+		if err == io.EOF {
+			return p.bad(unexpectedEOF(p.end, "a number"), signTokens[0].Position, p.end)
+		} else if err != nil {
+			return nil, err
 		}
 
-		// We require a number or constant to attach the signs to.
+		// We require a number or constant to attach the signs to, unless the
+		// signs are also prefix operators.
 		if t.Type != token.NumberToken && t.Type != token.ConstantToken {
-			return nil, fmt.Errorf("unexpected %s, expected a number or constant at %d", t, t.Position)
+			if !p.prefixes(signTokens) {
+				return p.badSigned(t, signTokens[0].Position)
+			}
+
+			p.backup(t)
+
+			return p.prefix(signTokens)
 		}
 
-		// Collapse the signs and attach them to the value to be parsed.
-		return ast.Lit{Type: t.Type, Value: collapseSigns(signs) + t.Value}, nil
+		return signed(signTokens, t), nil
 	}
 
 	// Numbers and constants are just literal values.
 	if t.Type == token.NumberToken || t.Type == token.ConstantToken {
-		return ast.Lit{Type: t.Type, Value: t.Value}, nil
+		return ast.Lit{Type: t.Type, Value: t.Value, ValuePos: t.Position}, nil
 	}
 
 	// Handle expressions in parentheses.
 	if t.Type == token.ParenthesisToken && t.Value == "(" {
-		// Evaluate the expression after the opening parenthesis.
-		expr, err := p.expression(1)
+		return p.group()
+	}
+
+	if t.Type == token.OperatorToken && p.prefixes([]token.Token{t}) {
+		return p.prefix([]token.Token{t})
+	}
+
+	// Leave operators and closing parentheses for the caller to pick up from.
+	if p.recovering && p.synchronising(t) {
+		p.backup(t)
+	}
 
+	return p.bad(unexpected(t, "a factor"), t.Position, t.Position)
+}
+
+// sign returns whether t is a sign.
+func sign(t token.Token) bool {
+	return t.Type == token.OperatorToken && (t.Value == "+" || t.Value == "-")
+}
+
+// signs consumes the signs following t, which is a sign that has already been
+// consumed, and returns them along with the token after them.
+func (p *parser) signs(t token.Token) ([]token.Token, token.Token, error) {
+	ts := []token.Token{t}
+
+	for {
+		next, err := p.next()
 		if err != nil {
+			return ts, token.Token{}, err
+		}
+
+		if !sign(next) {
+			return ts, next, nil
+		}
+
+		ts = append(ts, next)
+	}
+}
+
+// signed collapses signs and attaches them to t, which is a number or
+// constant.
+func signed(signs []token.Token, t token.Token) ast.Lit {
+	var b strings.Builder
+
+	for _, s := range signs {
+		b.WriteString(s.Value)
+	}
+
+	return ast.Lit{
+		Type:     t.Type,
+		Value:    collapseSigns(b.String()) + t.Value,
+		SignPos:  signs[0].Position,
+		ValuePos: t.Position,
+	}
+}
+
+// prefixes returns whether every one of ts is a prefix operator.
+func (p *parser) prefixes(ts []token.Token) bool {
+	for _, t := range ts {
+		if _, ok := p.options.Prefix[t.Value]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// prefix parses the operand of the prefix operators ops, which have already
+// been consumed, and applies them to it from the innermost out.
+func (p *parser) prefix(ops []token.Token) (ast.Node, error) {
+	last := ops[len(ops)-1]
+
+	x, err := p.expression(operandPrecedence(p.options.Prefix[last.Value]))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(ops) - 1; i >= 0; i-- {
+		x = ast.UnaryExpr{X: x, Op: ops[i].Value, OpPos: ops[i].Position}
+	}
+
+	return x, nil
+}
+
+// badSigned handles finding t, which isn't a number or constant, after the
+// signs starting at signPos.
+func (p *parser) badSigned(t token.Token, signPos int) (ast.Node, error) {
+	if err := p.error(unexpected(t, "a number or constant")); err != nil {
+		return nil, err
+	}
+
+	// Parse and throw away anything in parentheses, so that its contents
+	// aren't mistaken for trailing tokens.
+	if t.Type == token.ParenthesisToken && t.Value == "(" {
+		if _, err := p.group(); err != nil {
 			return nil, err
 		}
 
-		t, err = p.next()
+		return ast.BadExpr{From: signPos, To: p.end}, nil
+	}
+
+	if p.synchronising(t) {
+		p.backup(t)
+		return ast.BadExpr{From: signPos, To: t.Position}, nil
+	}
+
+	return ast.BadExpr{From: signPos, To: p.end}, nil
+}
+
+// group parses the rest of an expression in parentheses, after the opening
+// parenthesis.
+func (p *parser) group() (ast.Node, error) {
+	p.groups++
+	defer func() { p.groups-- }()
+
+	// Evaluate the expression after the opening parenthesis.
+	expr, err := p.expression(1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, err := p.next()
 
 		if err == io.EOF {
-			return nil, errors.New("unexpected EOF, expected closing parenthesis")
+			// Carry on as if the parenthesis was closed.
+			return expr, p.error(unexpectedEOF(p.end, "closing parenthesis"))
 		} else if err != nil {
 			return nil, err
 		}
 
 		// We expect a closing parenthesis now, as we've already evaluated the
 		// inner expression.
-		if t.Type != token.ParenthesisToken || t.Value != ")" {
-			return nil, fmt.Errorf("unexpected %s, expected closing parenthesis at %d", t, t.Position)
+		if t.Type == token.ParenthesisToken && t.Value == ")" {
+			return expr, nil
+		}
+
+		if err := p.error(unexpected(t, "closing parenthesis")); err != nil {
+			return nil, err
+		}
+
+		if expr, err = p.resume(expr, t); err != nil {
+			return nil, err
 		}
+	}
+}
+
+// root parses a whole expression, using the parser chosen by the options.
+func (p *parser) root() (ast.Node, error) {
+	var node ast.Node
+	var err error
 
-		return expr, nil
+	if p.options.ShuntingYard {
+		node, err = p.shunt()
+	} else {
+		node, err = p.expression(1)
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("unexpected %s, expected a factor at %d", t, t.Position)
+	if err := p.checkDepth(node); err != nil {
+		return nil, err
+	}
+
+	return node, nil
 }
 
-func ParseScanner(s *token.Scanner) (ast.Node, error) {
-	p := parser{scanner: s}
+// resume skips tokens, starting with t which has already been consumed, up to
+// the next operator and carries on parsing from there. The skipped tokens and
+// anything they're combined with are marked as bad, along with node, which is
+// the expression before them.
+func (p *parser) resume(node ast.Node, t token.Token) (ast.Node, error) {
+	if err := p.skip(t); err != nil {
+		return nil, err
+	}
 
-	node, err := p.expression(1)
+	rest, err := p.binary(ast.BadExpr{From: t.Position, To: p.end}, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.BadExpr{From: node.Pos(), To: rest.End()}, nil
+}
+
+// ParseScanner parses an infix expression from s using the operators in o.
+func (o Options) ParseScanner(s *token.Scanner) (ast.Node, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	p := parser{scanner: s, options: o}
+
+	node, err := p.root()
 	if err != nil {
 		return nil, err
 	}
@@ -232,13 +536,157 @@ func ParseScanner(s *token.Scanner) (ast.Node, error) {
 	}
 
 	// There are more tokens after the expression.
-	return nil, fmt.Errorf("unexpected trailing %s at %d", t, t.Position)
+	return nil, trailing(t)
+}
+
+// ParseEquationScanner parses an equation such as "2 * x = 4" from s using
+// the operators in o, returning the left and right hand sides.
+func (o Options) ParseEquationScanner(s *token.Scanner) (ast.Node, ast.Node, error) {
+	if err := o.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	p := parser{scanner: s, options: o}
+
+	lhs, err := p.root()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t, err := p.next()
+	if err == io.EOF {
+		return nil, nil, unexpectedEOF(p.end, "\"=\"")
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if t.Type != token.OperatorToken || t.Value != "=" {
+		return nil, nil, unexpected(t, "\"=\"")
+	}
+
+	rhs, err := p.root()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t, err = p.peek()
+	if err == io.EOF {
+		// No trailing tokens.
+		return lhs, rhs, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	// There are more tokens after the equation.
+	return nil, nil, trailing(t)
+}
+
+func (o Options) ParseEquationReader(r io.Reader) (ast.Node, ast.Node, error) {
+	return o.ParseEquationScanner(o.scanner(r))
+}
+
+func (o Options) ParseEquationString(s string) (ast.Node, ast.Node, error) {
+	return o.ParseEquationReader(strings.NewReader(s))
+}
+
+// ParseRecoverScanner parses an expression like o.ParseScanner, but rather than
+// stopping at the first syntax error it skips to the next operator or closing
+// parenthesis and carries on. Parts of the expression which couldn't be parsed
+// are replaced by ast.BadExpr nodes.
+//
+// The partial tree is returned along with an ErrorList holding every syntax
+// error, or a nil error if there were none. If the source can't be read then
+// only the read error is returned.
+func (o Options) ParseRecoverScanner(s *token.Scanner) (ast.Node, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	p := parser{scanner: s, options: o, recovering: true}
+
+	node, err := p.expression(1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, err := p.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		p.record(trailing(t))
+
+		// Unmatched closing parentheses can be dropped without losing
+		// anything, but other tokens can't be combined with the expression.
+		if t.Type == token.ParenthesisToken && t.Value == ")" {
+			node, err = p.binary(node, 1)
+		} else {
+			node, err = p.resume(node, t)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.checkDepth(node); err != nil {
+		return nil, err
+	}
+
+	return node, p.errors.Err()
+}
+
+func (o Options) ParseRecoverReader(r io.Reader) (ast.Node, error) {
+	return o.ParseRecoverScanner(o.scanner(r))
+}
+
+func (o Options) ParseRecoverString(s string) (ast.Node, error) {
+	return o.ParseRecoverReader(strings.NewReader(s))
+}
+
+func (o Options) ParseReader(r io.Reader) (ast.Node, error) {
+	return o.ParseScanner(o.scanner(r))
+}
+
+func (o Options) ParseString(s string) (ast.Node, error) {
+	return o.ParseReader(strings.NewReader(s))
+}
+
+func ParseScanner(s *token.Scanner) (ast.Node, error) {
+	return defaultOptions.ParseScanner(s)
 }
 
 func ParseReader(r io.Reader) (ast.Node, error) {
-	return ParseScanner(token.NewScanner(r))
+	return defaultOptions.ParseReader(r)
 }
 
 func ParseString(s string) (ast.Node, error) {
-	return ParseReader(strings.NewReader(s))
+	return defaultOptions.ParseString(s)
+}
+
+func ParseEquationScanner(s *token.Scanner) (ast.Node, ast.Node, error) {
+	return defaultOptions.ParseEquationScanner(s)
+}
+
+func ParseEquationReader(r io.Reader) (ast.Node, ast.Node, error) {
+	return defaultOptions.ParseEquationReader(r)
+}
+
+func ParseEquationString(s string) (ast.Node, ast.Node, error) {
+	return defaultOptions.ParseEquationString(s)
+}
+
+func ParseRecoverScanner(s *token.Scanner) (ast.Node, error) {
+	return defaultOptions.ParseRecoverScanner(s)
+}
+
+func ParseRecoverReader(r io.Reader) (ast.Node, error) {
+	return defaultOptions.ParseRecoverReader(r)
+}
+
+func ParseRecoverString(s string) (ast.Node, error) {
+	return defaultOptions.ParseRecoverString(s)
 }
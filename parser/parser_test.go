@@ -16,98 +16,114 @@ func TestParser(t *testing.T) {
 	}{
 		{
 			"1",
-			ast.Lit{Type: token.NumberToken, Value: "1"},
+			ast.Lit{Type: token.NumberToken, Value: "1", ValuePos: 0},
 		},
 		{
 			"1.0",
-			ast.Lit{Type: token.NumberToken, Value: "1.0"},
+			ast.Lit{Type: token.NumberToken, Value: "1.0", ValuePos: 0},
 		},
 		{
 			"-5 + -3 + +5",
 			ast.BinaryExpr{
 				Left: ast.BinaryExpr{
-					Left:  ast.Lit{Type: token.NumberToken, Value: "-5"},
-					Right: ast.Lit{Type: token.NumberToken, Value: "-3"},
+					Left:  ast.Lit{Type: token.NumberToken, Value: "-5", SignPos: 0, ValuePos: 1},
+					Right: ast.Lit{Type: token.NumberToken, Value: "-3", SignPos: 5, ValuePos: 6},
 					Op:    "+",
+					OpPos: 3,
 				},
-				Right: ast.Lit{Type: token.NumberToken, Value: "+5"},
+				Right: ast.Lit{Type: token.NumberToken, Value: "+5", SignPos: 10, ValuePos: 11},
 				Op:    "+",
+				OpPos: 8,
 			},
 		},
 		{
 			"0 + 2 / .3",
 			ast.BinaryExpr{
-				Left: ast.Lit{Type: token.NumberToken, Value: "0"},
+				Left: ast.Lit{Type: token.NumberToken, Value: "0", ValuePos: 0},
 				Right: ast.BinaryExpr{
-					Left:  ast.Lit{Type: token.NumberToken, Value: "2"},
-					Right: ast.Lit{Type: token.NumberToken, Value: ".3"},
+					Left:  ast.Lit{Type: token.NumberToken, Value: "2", ValuePos: 4},
+					Right: ast.Lit{Type: token.NumberToken, Value: ".3", ValuePos: 8},
 					Op:    "/",
+					OpPos: 6,
 				},
-				Op: "+",
+				Op:    "+",
+				OpPos: 2,
 			},
 		},
 		{
 			"(1 * 2) + (3 - 4)",
 			ast.BinaryExpr{
 				Left: ast.BinaryExpr{
-					Left:  ast.Lit{Type: token.NumberToken, Value: "1"},
-					Right: ast.Lit{Type: token.NumberToken, Value: "2"},
+					Left:  ast.Lit{Type: token.NumberToken, Value: "1", ValuePos: 1},
+					Right: ast.Lit{Type: token.NumberToken, Value: "2", ValuePos: 5},
 					Op:    "*",
+					OpPos: 3,
 				},
 				Right: ast.BinaryExpr{
-					Left:  ast.Lit{Type: token.NumberToken, Value: "3"},
-					Right: ast.Lit{Type: token.NumberToken, Value: "4"},
+					Left:  ast.Lit{Type: token.NumberToken, Value: "3", ValuePos: 11},
+					Right: ast.Lit{Type: token.NumberToken, Value: "4", ValuePos: 15},
 					Op:    "-",
+					OpPos: 13,
 				},
-				Op: "+",
+				Op:    "+",
+				OpPos: 8,
 			},
 		},
 		{
 			"3 + 4 * 2 / (1 - 5) ^ 2 ^ 3",
 			ast.BinaryExpr{
-				Left: ast.Lit{Type: token.NumberToken, Value: "3"},
+				Left: ast.Lit{Type: token.NumberToken, Value: "3", ValuePos: 0},
 				Right: ast.BinaryExpr{
 					Left: ast.BinaryExpr{
-						Left:  ast.Lit{Type: token.NumberToken, Value: "4"},
-						Right: ast.Lit{Type: token.NumberToken, Value: "2"},
+						Left:  ast.Lit{Type: token.NumberToken, Value: "4", ValuePos: 4},
+						Right: ast.Lit{Type: token.NumberToken, Value: "2", ValuePos: 8},
 						Op:    "*",
+						OpPos: 6,
 					},
 					Right: ast.BinaryExpr{
 						Left: ast.BinaryExpr{
-							Left:  ast.Lit{Type: token.NumberToken, Value: "1"},
-							Right: ast.Lit{Type: token.NumberToken, Value: "5"},
+							Left:  ast.Lit{Type: token.NumberToken, Value: "1", ValuePos: 13},
+							Right: ast.Lit{Type: token.NumberToken, Value: "5", ValuePos: 17},
 							Op:    "-",
+							OpPos: 15,
 						},
 						Right: ast.BinaryExpr{
-							Left:  ast.Lit{Type: token.NumberToken, Value: "2"},
-							Right: ast.Lit{Type: token.NumberToken, Value: "3"},
+							Left:  ast.Lit{Type: token.NumberToken, Value: "2", ValuePos: 22},
+							Right: ast.Lit{Type: token.NumberToken, Value: "3", ValuePos: 26},
 							Op:    "^",
+							OpPos: 24,
 						},
-						Op: "^",
+						Op:    "^",
+						OpPos: 20,
 					},
-					Op: "/",
+					Op:    "/",
+					OpPos: 10,
 				},
-				Op: "+",
+				Op:    "+",
+				OpPos: 2,
 			},
 		},
 		{
 			"2 * π",
 			ast.BinaryExpr{
-				Left:  ast.Lit{Type: token.NumberToken, Value: "2"},
-				Right: ast.Lit{Type: token.ConstantToken, Value: "π"},
+				Left:  ast.Lit{Type: token.NumberToken, Value: "2", ValuePos: 0},
+				Right: ast.Lit{Type: token.ConstantToken, Value: "π", ValuePos: 4},
 				Op:    "*",
+				OpPos: 2,
 			},
 		},
 		{
 			"3.141 / Pi + 2",
 			ast.BinaryExpr{
 				Left: ast.BinaryExpr{
-					Left:  ast.Lit{Type: token.NumberToken, Value: "3.141"},
-					Right: ast.Lit{Type: token.ConstantToken, Value: "Pi"},
+					Left:  ast.Lit{Type: token.NumberToken, Value: "3.141", ValuePos: 0},
+					Right: ast.Lit{Type: token.ConstantToken, Value: "Pi", ValuePos: 8},
 					Op:    "/",
+					OpPos: 6,
 				},
-				Right: ast.Lit{Type: token.NumberToken, Value: "2"},
+				Right: ast.Lit{Type: token.NumberToken, Value: "2", ValuePos: 13},
 				Op:    "+",
+				OpPos: 11,
 			},
 		},
 		{
@@ -115,15 +131,18 @@ func TestParser(t *testing.T) {
 			ast.BinaryExpr{
 				Left: ast.BinaryExpr{
 					Left: ast.BinaryExpr{
-						Left:  ast.Lit{Type: token.ConstantToken, Value: "-Pi"},
-						Right: ast.Lit{Type: token.ConstantToken, Value: "-Pi"},
+						Left:  ast.Lit{Type: token.ConstantToken, Value: "-Pi", SignPos: 0, ValuePos: 1},
+						Right: ast.Lit{Type: token.ConstantToken, Value: "-Pi", SignPos: 6, ValuePos: 7},
 						Op:    "+",
+						OpPos: 4,
 					},
-					Right: ast.Lit{Type: token.ConstantToken, Value: "Pi"},
+					Right: ast.Lit{Type: token.ConstantToken, Value: "Pi", ValuePos: 12},
 					Op:    "+",
+					OpPos: 10,
 				},
-				Right: ast.Lit{Type: token.ConstantToken, Value: "Pi"},
+				Right: ast.Lit{Type: token.ConstantToken, Value: "Pi", ValuePos: 17},
 				Op:    "+",
+				OpPos: 15,
 			},
 		},
 	}
@@ -145,3 +164,189 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+func TestParserPositions(t *testing.T) {
+	cases := []struct {
+		s        string
+		pos, end int
+	}{
+		{"1", 0, 1},
+		{"  3.141 ", 2, 7},
+		{"- - +π", 0, 6},
+		{"1 + 2 * 3", 0, 9},
+		{"(1 + 2) * 3", 1, 11},
+		{"Pi ^ (2 - -Inf)", 0, 14},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n.Pos() != c.pos {
+				t.Errorf("expected position to be %d but got %d", c.pos, n.Pos())
+			}
+
+			if n.End() != c.end {
+				t.Errorf("expected end to be %d but got %d", c.end, n.End())
+			}
+		})
+	}
+}
+
+func TestParseEquation(t *testing.T) {
+	cases := []struct {
+		s, lhs, rhs string
+	}{
+		{"x = 1", "x", "1"},
+		{"1000 * (1 + r) ^ 10 = 2000", "(1000 * ((1 + r) ^ 10))", "2000"},
+		{"x=-y+1", "x", "(-y + 1)"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			lhs, rhs, err := parser.ParseEquationString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if lhs.String() != c.lhs {
+				t.Errorf("expected left hand side %s but got %s", c.lhs, lhs)
+			}
+
+			if rhs.String() != c.rhs {
+				t.Errorf("expected right hand side %s but got %s", c.rhs, rhs)
+			}
+		})
+	}
+}
+
+func TestParseEquationErrors(t *testing.T) {
+	cases := []struct {
+		s, err string
+	}{
+		{"x + 1", `unexpected EOF, expected "="`},
+		{"x )", `unexpected ")", expected "=" at 2`},
+		{"x = ", "unexpected EOF, expected a factor"},
+		{"x = 1 = 2", `unexpected trailing "=" at 6`},
+		{"(x = 1) = 2", `unexpected "=", expected closing parenthesis at 3`},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, _, err := parser.ParseEquationString(c.s)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if err.Error() != c.err {
+				t.Fatalf("expected error %q but got %q", c.err, err)
+			}
+		})
+	}
+
+	// Equals signs aren't valid outside of equations.
+	if _, err := parser.ParseString("x = 1"); err == nil || err.Error() != `unexpected trailing "=" at 2` {
+		t.Fatalf("expected trailing equals sign error but got %v", err)
+	}
+}
+
+func TestParseRecover(t *testing.T) {
+	cases := []struct {
+		s, n string
+		bad  [][2]int
+		errs []string
+	}{
+		{"1 + 2", "(1 + 2)", nil, nil},
+		{"1 + * 2", "(1 + (BadExpr * 2))", [][2]int{{4, 4}}, []string{`unexpected "*", expected a factor at 4`}},
+		{"1 + 2) * 3", "((1 + 2) * 3)", nil, []string{`unexpected trailing ")" at 5`}},
+		{"(1 + 2", "(1 + 2)", nil, []string{"unexpected EOF, expected closing parenthesis"}},
+		{"1 2 + 3", "BadExpr", [][2]int{{0, 7}}, []string{`unexpected trailing "2" at 2`}},
+		{"(1 2) * 3", "(BadExpr * 3)", [][2]int{{1, 4}}, []string{`unexpected "2", expected closing parenthesis at 3`}},
+		{"-(1 + 2) * x", "(BadExpr * x)", [][2]int{{0, 8}}, []string{`unexpected "(", expected a number or constant at 1`}},
+		{"- * 3", "(BadExpr * 3)", [][2]int{{0, 2}}, []string{`unexpected "*", expected a number or constant at 2`}},
+		{"", "BadExpr", [][2]int{{0, 0}}, []string{"unexpected EOF, expected a factor"}},
+		{"1 + )", "(1 + BadExpr)", [][2]int{{4, 5}}, []string{`unexpected ")", expected a factor at 4`}},
+		{"1 + ) 2", "(1 + 2)", nil, []string{`unexpected ")", expected a factor at 4`}},
+		{"1 + ) ) 2", "(1 + 2)", nil, []string{`unexpected ")", expected a factor at 4`, `unexpected ")", expected a factor at 6`}},
+		{"(1 + ) * 2", "((1 + BadExpr) * 2)", [][2]int{{5, 5}}, []string{`unexpected ")", expected a factor at 5`}},
+		{
+			"1 +* 2 -/ 3",
+			"((1 + (BadExpr * 2)) - (BadExpr / 3))",
+			[][2]int{{3, 3}, {8, 8}},
+			[]string{`unexpected "*", expected a factor at 3`, `unexpected "/", expected a factor at 8`},
+		},
+		{
+			"(1 + (2 3) + 4",
+			"((1 + BadExpr) + 4)",
+			[][2]int{{6, 9}},
+			[]string{`unexpected "3", expected closing parenthesis at 8`, "unexpected EOF, expected closing parenthesis"},
+		},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseRecoverString(c.s)
+
+			var errs []string
+
+			if err != nil {
+				list, ok := err.(parser.ErrorList)
+				if !ok {
+					t.Fatalf("expected an error list but got %T", err)
+				}
+
+				for _, e := range list {
+					errs = append(errs, e.Error())
+				}
+			}
+
+			if !reflect.DeepEqual(errs, c.errs) {
+				t.Fatalf("expected errors %q but got %q", c.errs, errs)
+			}
+
+			if n.String() != c.n {
+				t.Fatalf("expected %q, got %q", c.n, n)
+			}
+
+			var bad [][2]int
+
+			ast.Inspect(n, func(n ast.Node) bool {
+				if b, ok := n.(ast.BadExpr); ok {
+					bad = append(bad, [2]int{b.From, b.To})
+				}
+
+				return true
+			})
+
+			if !reflect.DeepEqual(bad, c.bad) {
+				t.Fatalf("expected bad expressions at %v but got %v", c.bad, bad)
+			}
+		})
+	}
+}
+
+func TestParseRecoverPositions(t *testing.T) {
+	_, err := parser.ParseRecoverString("(1 + ) * (2 3")
+
+	list, ok := err.(parser.ErrorList)
+	if !ok {
+		t.Fatalf("expected an error list but got %v", err)
+	}
+
+	var positions []int
+
+	for _, e := range list {
+		positions = append(positions, e.Pos)
+	}
+
+	if expected := []int{5, 12, 13}; !reflect.DeepEqual(positions, expected) {
+		t.Fatalf("expected errors at %v but got %v", expected, positions)
+	}
+
+	if expected := `unexpected ")", expected a factor at 5 (and 2 more errors)`; err.Error() != expected {
+		t.Fatalf("expected error %q but got %q", expected, err)
+	}
+}
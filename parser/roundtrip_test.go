@@ -0,0 +1,64 @@
+package parser_test
+
+import (
+	"bufio"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestRoundTrip checks that every expression in the ast package's test data
+// is unchanged by printing it in reverse Polish notation or as an
+// S-expression and parsing it back.
+func TestRoundTrip(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "ast", "testdata", "exprs.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Match the options used to render the golden files.
+	o := parser.DefaultOptions()
+	o.Prefix["-"] = parser.Operator{Precedence: 2, Associativity: parser.RightAssociative}
+	o.Prefix["~"] = parser.Operator{Precedence: 4, Associativity: parser.RightAssociative}
+
+	formats := []struct {
+		name  string
+		print func(ast.Node) string
+		parse func(string) (ast.Node, error)
+	}{
+		{"rpn", ast.RPN, o.ParseRPNString},
+		{"sexpr", ast.SExpr, o.ParseSExprString},
+	}
+
+	s := bufio.NewScanner(f)
+
+	for line := 1; s.Scan(); line++ {
+		n, err := o.ParseString(s.Text())
+		if err != nil {
+			t.Fatalf("line %d: %s", line, err)
+		}
+
+		for _, format := range formats {
+			t.Run(format.name+" line "+strconv.Itoa(line), func(t *testing.T) {
+				printed := format.print(n)
+
+				parsed, err := format.parse(printed)
+				if err != nil {
+					t.Fatalf("%q: %s", printed, err)
+				}
+
+				if parsed.String() != n.String() {
+					t.Fatalf("%q: expected %s but got %s", printed, n, parsed)
+				}
+			})
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,211 @@
+package parser
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+	"io"
+	"strings"
+)
+
+// isSign returns whether the operator t is a sign attached to the token
+// directly after it, rather than an operator in its own right.
+func (p *parser) isSign(t token.Token) (bool, error) {
+	if t.Value != "+" && t.Value != "-" {
+		return false, nil
+	}
+
+	next, err := p.peek()
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// Signs are written immediately before the thing they apply to, such as
+	// "-5", and operators are separated from it, such as "3 5 -".
+	if next.Position != t.Position+1 {
+		return false, nil
+	}
+
+	return next.Type == token.OperatorToken || next.Type == token.NumberToken || next.Type == token.ConstantToken, nil
+}
+
+// rpn parses a sequence of tokens in reverse Polish notation.
+func (p *parser) rpn() (ast.Node, error) {
+	var stack []ast.Node
+
+	for {
+		t, err := p.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		// Numbers and constants are just literal values.
+		if t.Type == token.NumberToken || t.Type == token.ConstantToken {
+			stack = append(stack, ast.Lit{Type: t.Type, Value: t.Value, ValuePos: t.Position})
+			continue
+		}
+
+		// Prefix operators are written in parentheses, such as "x (-)", so
+		// that they can't be mistaken for binary operators.
+		if t.Type == token.ParenthesisToken && t.Value == "(" {
+			if stack, err = p.rpnPrefix(stack); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if t.Type != token.OperatorToken {
+			return nil, unexpected(t, "an operand or operator")
+		}
+
+		sign, err := p.isSign(t)
+		if err != nil {
+			return nil, err
+		}
+
+		// Collapse the signs onto the value they're attached to.
+		if sign {
+			signs := t.Value
+			signPos := t.Position
+
+			for sign {
+				t, err = p.next()
+				if err != nil {
+					return nil, err
+				}
+
+				signs += t.Value
+
+				if sign, err = p.isSign(t); err != nil {
+					return nil, err
+				}
+			}
+
+			if t.Type != token.NumberToken && t.Type != token.ConstantToken {
+				return nil, unexpected(t, "a number or constant")
+			}
+
+			stack = append(stack, ast.Lit{
+				Type:     t.Type,
+				Value:    collapseSigns(signs) + t.Value,
+				SignPos:  signPos,
+				ValuePos: t.Position,
+			})
+
+			continue
+		}
+
+		// Postfix operators take priority, the same as they do infix.
+		if _, ok := p.options.Postfix[t.Value]; ok {
+			if len(stack) < 1 {
+				return nil, unexpected(t, "an operand")
+			}
+
+			x := stack[len(stack)-1]
+			stack[len(stack)-1] = ast.UnaryExpr{X: x, Op: t.Value, OpPos: t.Position, Postfix: true}
+			continue
+		}
+
+		if _, valid := p.options.Binary[t.Value]; !valid {
+			return nil, unknownOperator(t)
+		}
+
+		if len(stack) < 2 {
+			return nil, unexpected(t, "two operands")
+		}
+
+		left, right := stack[len(stack)-2], stack[len(stack)-1]
+		stack = append(stack[:len(stack)-2], ast.BinaryExpr{Left: left, Right: right, Op: t.Value, OpPos: t.Position})
+	}
+
+	if len(stack) == 0 {
+		return nil, unexpectedEOF(p.end, "an operand")
+	}
+
+	if len(stack) > 1 {
+		return nil, unexpectedEOF(p.end, "an operator")
+	}
+
+	if err := p.checkDepth(stack[0]); err != nil {
+		return nil, err
+	}
+
+	return stack[0], nil
+}
+
+// rpnPrefix parses the rest of a prefix operator in parentheses, after the
+// opening parenthesis, and applies it to the top of the stack.
+func (p *parser) rpnPrefix(stack []ast.Node) ([]ast.Node, error) {
+	op, err := p.next()
+	if err == io.EOF {
+		return nil, unexpectedEOF(p.end, "an operator")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if op.Type != token.OperatorToken {
+		return nil, unexpected(op, "an operator")
+	}
+
+	if _, ok := p.options.Prefix[op.Value]; !ok {
+		return nil, unknownOperator(op)
+	}
+
+	t, err := p.next()
+	if err == io.EOF {
+		return nil, unexpectedEOF(p.end, "closing parenthesis")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if t.Type != token.ParenthesisToken || t.Value != ")" {
+		return nil, unexpected(t, "closing parenthesis")
+	}
+
+	if len(stack) < 1 {
+		return nil, unexpected(op, "an operand")
+	}
+
+	x := stack[len(stack)-1]
+	stack[len(stack)-1] = ast.UnaryExpr{X: x, Op: op.Value, OpPos: op.Position}
+
+	return stack, nil
+}
+
+// ParseRPNScanner parses an expression written in reverse Polish notation,
+// such as "3 4 + 2 *", using the operators in o. Signs are attached to the
+// value directly after them, so "3 -4 +" adds -4 to 3 and "3 4 -" subtracts 4
+// from 3. Postfix operators are written as they are infix, such as "3 !", and
+// prefix operators are written in parentheses, such as "x 1 + (-)".
+func (o Options) ParseRPNScanner(s *token.Scanner) (ast.Node, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	p := parser{scanner: s, options: o}
+	return p.rpn()
+}
+
+func (o Options) ParseRPNReader(r io.Reader) (ast.Node, error) {
+	return o.ParseRPNScanner(o.scanner(r))
+}
+
+func (o Options) ParseRPNString(s string) (ast.Node, error) {
+	return o.ParseRPNReader(strings.NewReader(s))
+}
+
+func ParseRPNScanner(s *token.Scanner) (ast.Node, error) {
+	return defaultOptions.ParseRPNScanner(s)
+}
+
+func ParseRPNReader(r io.Reader) (ast.Node, error) {
+	return defaultOptions.ParseRPNReader(r)
+}
+
+func ParseRPNString(s string) (ast.Node, error) {
+	return defaultOptions.ParseRPNString(s)
+}
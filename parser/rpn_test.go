@@ -0,0 +1,163 @@
+package parser_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/token"
+	"strconv"
+	"testing"
+)
+
+func TestParseRPN(t *testing.T) {
+	cases := []struct {
+		rpn, infix string
+	}{
+		{"1", "1"},
+		{"-5", "-5"},
+		{"--Pi", "- -Pi"},
+		{"3 4 + 2 *", "(3 + 4) * 2"},
+		{"3 -4 +", "3 + -4"},
+		{"3 4 -", "3 - 4"},
+		{"3 4-", "3 - 4"},
+		{"1 2 3 ^ ^", "1 ^ 2 ^ 3"},
+		{"2 π *", "2 * π"},
+		{"3 4 2 * 1 5 - 2 3 ^ ^ / +", "3 + 4 * 2 / (1 - 5) ^ 2 ^ 3"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseRPNString(c.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := parser.ParseString(c.infix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n.String() != expected.String() {
+				t.Fatalf("expected %s but got %s", expected, n)
+			}
+
+			// Printing it back out should give the same tree.
+			printed, err := parser.ParseRPNString(ast.RPN(n))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if printed.String() != n.String() {
+				t.Fatalf("expected %s to round trip but got %s", n, printed)
+			}
+		})
+	}
+}
+
+func TestParseRPNPositions(t *testing.T) {
+	n, err := parser.ParseRPNString("3 -x +")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ast.BinaryExpr{
+		Left:  ast.Lit{Type: token.NumberToken, Value: "3", ValuePos: 0},
+		Right: ast.Lit{Type: token.ConstantToken, Value: "-x", SignPos: 2, ValuePos: 3},
+		Op:    "+",
+		OpPos: 5,
+	}
+
+	if n != expected {
+		t.Fatalf("expected %#v but got %#v", expected, n)
+	}
+}
+
+func TestParseRPNErrors(t *testing.T) {
+	cases := []struct {
+		s, err string
+	}{
+		{"", "unexpected EOF, expected an operand"},
+		{"1 2", "unexpected EOF, expected an operator"},
+		{"1 +", `unexpected "+", expected two operands at 2`},
+		{"1 2 ) +", `unexpected ")", expected an operand or operator at 4`},
+		{"1 2 ( +", `unknown operator "+" at 6`},
+		{"!", `unexpected "!", expected an operand at 0`},
+		{"1 -+", `unexpected "+", expected a number or constant at 3`},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, err := parser.ParseRPNString(c.s)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if err.Error() != c.err {
+				t.Fatalf("expected error %q but got %q", c.err, err)
+			}
+		})
+	}
+}
+
+func TestParseRPNUnary(t *testing.T) {
+	cases := []struct {
+		rpn, infix string
+	}{
+		{"5 !", "5!"},
+		{"5 ! 2 +", "5! + 2"},
+		{"x !!", "x!!"},
+		{"x ! !", "x! !"},
+		{"x (~)", "~x"},
+		{"x 1 + (-)", "-(x + 1)"},
+		{"1 x (-) -", "1 - -(x)"},
+		{"-2 (~) 3 **", "~-2 ** 3"},
+	}
+
+	o := dialect()
+	o.Binary["-"] = parser.Operator{Precedence: 2}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := o.ParseRPNString(c.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := o.ParseString(c.infix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n.String() != expected.String() {
+				t.Fatalf("expected %s but got %s", expected, n)
+			}
+		})
+	}
+}
+
+func TestParseRPNUnaryErrors(t *testing.T) {
+	cases := []struct {
+		s, err string
+	}{
+		{"(~)", `unexpected "~", expected an operand at 1`},
+		{"x (~", "unexpected EOF, expected closing parenthesis"},
+		{"x (~ 1", `unexpected "1", expected closing parenthesis at 5`},
+		{"x (1)", `unexpected "1", expected an operator at 3`},
+		{"x (", "unexpected EOF, expected an operator"},
+		{"x (!)", `unknown operator "!" at 3`},
+	}
+
+	o := dialect()
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, err := o.ParseRPNString(c.s)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if err.Error() != c.err {
+				t.Fatalf("expected error %q but got %q", c.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+	"io"
+	"strings"
+)
+
+// sexprAtom parses the number or constant starting with t, along with any
+// signs attached to it.
+func (p *parser) sexprAtom(t token.Token) (ast.Node, error) {
+	signs := ""
+	signPos := t.Position
+
+	// Signs are attached to the token directly after them, the same as in
+	// reverse Polish notation.
+	for t.Type == token.OperatorToken {
+		sign, err := p.isSign(t)
+		if err != nil {
+			return nil, err
+		}
+
+		if !sign {
+			return nil, unexpected(t, "a number, constant or list")
+		}
+
+		signs += t.Value
+
+		if t, err = p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Type != token.NumberToken && t.Type != token.ConstantToken {
+		return nil, unexpected(t, "a number, constant or list")
+	}
+
+	if signs == "" {
+		return ast.Lit{Type: t.Type, Value: t.Value, ValuePos: t.Position}, nil
+	}
+
+	return ast.Lit{
+		Type:     t.Type,
+		Value:    collapseSigns(signs) + t.Value,
+		SignPos:  signPos,
+		ValuePos: t.Position,
+	}, nil
+}
+
+// sexpr parses a single S-expression.
+func (p *parser) sexpr() (ast.Node, error) {
+	t, err := p.next()
+	if err == io.EOF {
+		return nil, unexpectedEOF(p.end, "a number, constant or list")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if t.Type != token.ParenthesisToken {
+		return p.sexprAtom(t)
+	}
+
+	if t.Value != "(" {
+		return nil, unexpected(t, "a number, constant or list")
+	}
+
+	if err := p.enter(t.Position); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	// Lists start with the operator.
+	op, err := p.next()
+	if err == io.EOF {
+		return nil, unexpectedEOF(p.end, "an operator")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if op.Type != token.OperatorToken {
+		return nil, unexpected(op, "an operator")
+	}
+
+	_, binary := p.options.Binary[op.Value]
+	_, prefix := p.options.Prefix[op.Value]
+	_, postfix := p.options.Postfix[op.Value]
+
+	if !binary && !prefix && !postfix && !sign(op) {
+		return nil, unknownOperator(op)
+	}
+
+	var operands []ast.Node
+
+	for {
+		t, err := p.peek()
+		if err == io.EOF {
+			return nil, unexpectedEOF(p.end, "closing parenthesis")
+		} else if err != nil {
+			return nil, err
+		}
+
+		if t.Type == token.ParenthesisToken && t.Value == ")" {
+			break
+		}
+
+		operand, err := p.sexpr()
+		if err != nil {
+			return nil, err
+		}
+
+		operands = append(operands, operand)
+	}
+
+	// Consume the closing parenthesis.
+	end, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	// Unary operators have a single operand, preferring prefix operators
+	// when an operator is both.
+	if len(operands) == 1 && prefix {
+		return ast.UnaryExpr{X: operands[0], Op: op.Value, OpPos: op.Position}, nil
+	}
+
+	if len(operands) == 1 && postfix {
+		return ast.UnaryExpr{X: operands[0], Op: op.Value, OpPos: op.Position, Postfix: true}, nil
+	}
+
+	// Otherwise negating a single value is the same as giving it a sign.
+	if l, ok := singleLit(operands); ok && sign(op) {
+		signs := op.Value
+		if l.Negative() {
+			signs += "-"
+		}
+
+		l.Value = collapseSigns(signs) + l.Unsigned()
+		l.SignPos = op.Position
+		return l, nil
+	}
+
+	if len(operands) < 2 {
+		return nil, unexpected(end, "two operands")
+	}
+
+	if !binary {
+		return nil, unknownOperator(op)
+	}
+
+	// Fold the operands into left associated binary expressions.
+	left := operands[0]
+
+	for _, right := range operands[1:] {
+		left = ast.BinaryExpr{Left: left, Right: right, Op: op.Value, OpPos: op.Position}
+	}
+
+	return left, nil
+}
+
+// singleLit returns the only operand if it is a literal.
+func singleLit(operands []ast.Node) (ast.Lit, bool) {
+	if len(operands) != 1 {
+		return ast.Lit{}, false
+	}
+
+	l, ok := operands[0].(ast.Lit)
+	return l, ok
+}
+
+// ParseSExprScanner parses an expression written as an S-expression, such as
+// "(+ 1 (* 2 3))", using the operators in o. Operators with more than two
+// operands are folded into left associated binary expressions, so
+// "(- 1 2 3)" is the same as "(1 - 2) - 3". Prefix and postfix operators have
+// a single operand, such as "(! 3)".
+func (o Options) ParseSExprScanner(s *token.Scanner) (ast.Node, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	p := parser{scanner: s, options: o}
+
+	node, err := p.sexpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.checkDepth(node); err != nil {
+		return nil, err
+	}
+
+	t, err := p.peek()
+	if err == io.EOF {
+		// No trailing tokens.
+		return node, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// There are more tokens after the expression.
+	return nil, trailing(t)
+}
+
+func (o Options) ParseSExprReader(r io.Reader) (ast.Node, error) {
+	return o.ParseSExprScanner(o.scanner(r))
+}
+
+func (o Options) ParseSExprString(s string) (ast.Node, error) {
+	return o.ParseSExprReader(strings.NewReader(s))
+}
+
+func ParseSExprScanner(s *token.Scanner) (ast.Node, error) {
+	return defaultOptions.ParseSExprScanner(s)
+}
+
+func ParseSExprReader(r io.Reader) (ast.Node, error) {
+	return defaultOptions.ParseSExprReader(r)
+}
+
+func ParseSExprString(s string) (ast.Node, error) {
+	return defaultOptions.ParseSExprString(s)
+}
@@ -0,0 +1,87 @@
+package parser_test
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"strconv"
+	"testing"
+)
+
+func TestParseSExpr(t *testing.T) {
+	cases := []struct {
+		sexpr, infix string
+	}{
+		{"1", "1"},
+		{"-5", "-5"},
+		{"(- 5)", "-5"},
+		{"(- -Pi)", "+Pi"},
+		{"(+ 1 (* 2 3))", "1 + 2 * 3"},
+		{"(- 1 2 3)", "1 - 2 - 3"},
+		{"(^ 2 3 4)", "(2 ^ 3) ^ 4"},
+		{"(* (+ x 1)(- x 1))", "(x + 1) * (x - 1)"},
+		{"(/ -x (+ π -2))", "-x / (π + -2)"},
+		{"(+ 3 (/ (* 4 2) (^ (- 1 5) (^ 2 3))))", "3 + 4 * 2 / (1 - 5) ^ 2 ^ 3"},
+		{"(! 5)", "5!"},
+		{"(+ (! 5) 2)", "5! + 2"},
+		{"(% (!! x))", "x!!%"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseSExprString(c.sexpr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := parser.ParseString(c.infix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n.String() != expected.String() {
+				t.Fatalf("expected %s but got %s", expected, n)
+			}
+
+			// Printing it back out should give the same tree.
+			printed, err := parser.ParseSExprString(ast.SExpr(n))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if printed.String() != n.String() {
+				t.Fatalf("expected %s to round trip but got %s", n, printed)
+			}
+		})
+	}
+}
+
+func TestParseSExprErrors(t *testing.T) {
+	cases := []struct {
+		s, err string
+	}{
+		{"", "unexpected EOF, expected a number, constant or list"},
+		{"(", "unexpected EOF, expected an operator"},
+		{"(+ 1 2", "unexpected EOF, expected closing parenthesis"},
+		{"(1 2)", `unexpected "1", expected an operator at 1`},
+		{"(* 1)", `unexpected ")", expected two operands at 4`},
+		{"(+ (* 2 3))", `unexpected ")", expected two operands at 10`},
+		{"(+ 1 2) 3", `unexpected trailing "3" at 8`},
+		{")", `unexpected ")", expected a number, constant or list at 0`},
+		{"(+ 1 + 2)", `unexpected "+", expected a number, constant or list at 5`},
+		{"(! 1 2)", `unknown operator "!" at 1`},
+		{"(!)", `unexpected ")", expected two operands at 2`},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, err := parser.ParseSExprString(c.s)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if err.Error() != c.err {
+				t.Fatalf("expected error %q but got %q", c.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+	"io"
+	"math"
+)
+
+// pending is an operator or opening parenthesis waiting on the shunting-yard
+// operator stack.
+type pending struct {
+	// ops holds the operator, or the run of prefix operators which share an
+	// operand, and is empty for an opening parenthesis.
+	ops    []token.Token
+	prefix bool
+
+	// operand is the lowest precedence of operator which can be part of the
+	// operator's right hand side.
+	operand int
+}
+
+// yard holds the stacks used by the shunting-yard algorithm.
+type yard struct {
+	operands  []ast.Node
+	operators []pending
+	groups    int
+}
+
+// push adds op to the operator stack.
+func (y *yard) push(op pending) {
+	if len(op.ops) == 0 {
+		y.groups++
+	}
+
+	y.operators = append(y.operators, op)
+}
+
+// reduce applies every operator on top of the stack whose right hand side
+// can't include an operator of the provided precedence, stopping at the
+// innermost opening parenthesis.
+func (y *yard) reduce(precedence int) {
+	for len(y.operators) > 0 {
+		top := y.operators[len(y.operators)-1]
+
+		// Parentheses hold an expression of any precedence.
+		if len(top.ops) == 0 || precedence >= top.operand {
+			return
+		}
+
+		y.operators = y.operators[:len(y.operators)-1]
+		y.apply(top)
+	}
+}
+
+// close applies every operator down to the innermost opening parenthesis and
+// removes it, returning false if there isn't one.
+func (y *yard) close() bool {
+	y.reduce(math.MinInt)
+
+	if y.groups == 0 {
+		return false
+	}
+
+	y.operators = y.operators[:len(y.operators)-1]
+	y.groups--
+
+	return true
+}
+
+// apply replaces the operands of op on the operand stack with the result of
+// op.
+func (y *yard) apply(op pending) {
+	x := y.operands[len(y.operands)-1]
+	y.operands = y.operands[:len(y.operands)-1]
+
+	if op.prefix {
+		for i := len(op.ops) - 1; i >= 0; i-- {
+			x = ast.UnaryExpr{X: x, Op: op.ops[i].Value, OpPos: op.ops[i].Position}
+		}
+	} else {
+		left := y.operands[len(y.operands)-1]
+		x = ast.BinaryExpr{Left: left, Right: x, Op: op.ops[0].Value, OpPos: op.ops[0].Position}
+		y.operands = y.operands[:len(y.operands)-1]
+	}
+
+	y.operands = append(y.operands, x)
+}
+
+// shunt parses an expression with Dijkstra's shunting-yard algorithm, which
+// uses explicit stacks rather than recursion. It builds the same trees as
+// expression(1), and stops at the same tokens with the same errors.
+func (p *parser) shunt() (ast.Node, error) {
+	var y yard
+
+	for {
+		// Read operands, along with any prefix operators and opening
+		// parentheses before them.
+		t, err := p.next()
+		if err == io.EOF {
+			return nil, unexpectedEOF(p.end, "a factor")
+		} else if err != nil {
+			return nil, err
+		}
+
+		if sign(t) {
+			signTokens, next, err := p.signs(t)
+			if err == io.EOF {
+				return nil, unexpectedEOF(p.end, "a number")
+			} else if err != nil {
+				return nil, err
+			}
+
+			if next.Type == token.NumberToken || next.Type == token.ConstantToken {
+				y.operands = append(y.operands, signed(signTokens, next))
+			} else if p.prefixes(signTokens) {
+				p.backup(next)
+				y.push(pending{ops: signTokens, prefix: true, operand: p.prefixOperand(signTokens)})
+				continue
+			} else {
+				return nil, unexpected(next, "a number or constant")
+			}
+		} else if t.Type == token.NumberToken || t.Type == token.ConstantToken {
+			y.operands = append(y.operands, ast.Lit{Type: t.Type, Value: t.Value, ValuePos: t.Position})
+		} else if t.Type == token.ParenthesisToken && t.Value == "(" {
+			y.push(pending{})
+			continue
+		} else if t.Type == token.OperatorToken && p.prefixes([]token.Token{t}) {
+			y.push(pending{ops: []token.Token{t}, prefix: true, operand: p.prefixOperand([]token.Token{t})})
+			continue
+		} else {
+			return nil, unexpected(t, "a factor")
+		}
+
+		// Read postfix operators and closing parentheses until the next binary
+		// operator, or the end of the expression.
+		for {
+			t, err := p.peek()
+			if err == io.EOF {
+				if y.close() {
+					return nil, unexpectedEOF(p.end, "closing parenthesis")
+				}
+
+				return y.operands[0], nil
+			} else if err != nil {
+				return nil, err
+			}
+
+			if t.Type == token.ParenthesisToken && t.Value == ")" {
+				if !y.close() {
+					return y.operands[0], nil
+				}
+
+				if _, err := p.next(); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			// Anything other than an operator ends the expression.
+			if t.Type != token.OperatorToken || t.Value == "=" {
+				return p.shunted(&y, t)
+			}
+
+			if op, ok := p.options.Postfix[t.Value]; ok {
+				y.reduce(op.Precedence)
+
+				if _, err := p.next(); err != nil {
+					return nil, err
+				}
+
+				x := y.operands[len(y.operands)-1]
+				y.operands[len(y.operands)-1] = ast.UnaryExpr{X: x, Op: t.Value, OpPos: t.Position, Postfix: true}
+				continue
+			}
+
+			op, valid := p.options.Binary[t.Value]
+
+			if !valid {
+				return nil, unknownOperator(t)
+			}
+
+			y.reduce(op.Precedence)
+
+			if _, err := p.next(); err != nil {
+				return nil, err
+			}
+
+			y.push(pending{ops: []token.Token{t}, operand: operandPrecedence(op)})
+			break
+		}
+	}
+}
+
+// shunted finishes the expression in y at t, which can't be part of it. The
+// expression can only finish there if it isn't in parentheses.
+func (p *parser) shunted(y *yard, t token.Token) (ast.Node, error) {
+	if y.close() {
+		return nil, unexpected(t, "closing parenthesis")
+	}
+
+	return y.operands[0], nil
+}
+
+// prefixOperand returns the operand precedence of the run of prefix operators
+// ops, which is decided by the innermost one.
+func (p *parser) prefixOperand(ops []token.Token) int {
+	return operandPrecedence(p.options.Prefix[ops[len(ops)-1].Value])
+}
@@ -0,0 +1,236 @@
+package parser_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/parser"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// randomTokens appends the tokens of a random expression using the operators
+// in o to ts.
+func randomTokens(r *rand.Rand, o parser.Options, ts []string, depth int) []string {
+	pick := func(ops map[string]parser.Operator) string {
+		keys := make([]string, 0, len(ops))
+
+		for k := range ops {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		return keys[r.Intn(len(keys))]
+	}
+
+	switch n := r.Intn(6); {
+	case depth == 0 || n < 2:
+		operands := []string{"1", "2.5", "x", "-y", "+-3"}
+		ts = append(ts, operands[r.Intn(len(operands))])
+	case n == 2:
+		ts = append(ts, "(")
+		ts = randomTokens(r, o, ts, depth-1)
+		ts = append(ts, ")")
+	case n == 3 && len(o.Prefix) > 0:
+		ts = append(ts, pick(o.Prefix))
+		ts = randomTokens(r, o, ts, depth-1)
+	case n == 4 && len(o.Postfix) > 0:
+		ts = randomTokens(r, o, ts, depth-1)
+		ts = append(ts, pick(o.Postfix))
+	default:
+		ts = randomTokens(r, o, ts, depth-1)
+		ts = append(ts, pick(o.Binary))
+		ts = randomTokens(r, o, ts, depth-1)
+	}
+
+	return ts
+}
+
+// randomSource returns a random expression using the operators in o, which
+// is broken by adding, removing or changing tokens about half of the time.
+func randomSource(r *rand.Rand, o parser.Options, extra []string) string {
+	ts := randomTokens(r, o, nil, 5)
+
+	for r.Intn(2) == 0 {
+		i := r.Intn(len(ts) + 1)
+		junk := append([]string{"(", ")", "1", "x", "="}, extra...)
+
+		switch r.Intn(3) {
+		case 0:
+			ts = append(ts[:i], append([]string{junk[r.Intn(len(junk))]}, ts[i:]...)...)
+		case 1:
+			if i < len(ts) {
+				ts = append(ts[:i], ts[i+1:]...)
+			}
+		default:
+			if i < len(ts) {
+				ts[i] = junk[r.Intn(len(junk))]
+			}
+		}
+	}
+
+	// Spaces are mostly optional, so sometimes leave them out.
+	var b strings.Builder
+
+	for _, t := range ts {
+		b.WriteString(t)
+
+		if r.Intn(3) != 0 {
+			b.WriteString(" ")
+		}
+	}
+
+	return b.String()
+}
+
+// parseBoth parses s with and without the shunting-yard algorithm.
+func parseBoth(o parser.Options, s string) (string, string) {
+	format := func(n ast.Node, err error) string {
+		if err != nil {
+			return "error: " + err.Error()
+		}
+
+		// Check the positions as well as the structure of the tree.
+		return fmt.Sprintf("%#v", n)
+	}
+
+	climbing := format(o.ParseString(s))
+	o.ShuntingYard = true
+	shunting := format(o.ParseString(s))
+
+	return climbing, shunting
+}
+
+// TestShuntingYard checks that the shunting-yard parser agrees with the
+// precedence climbing parser on random input.
+func TestShuntingYard(t *testing.T) {
+	// Prefix and postfix operators which bind looser than binary operators,
+	// and binary operators of the same precedence with different
+	// associativity.
+	loose := parser.DefaultOptions()
+	loose.Binary["&"] = parser.Operator{Precedence: 1, Associativity: parser.RightAssociative}
+	loose.Binary["<<"] = parser.Operator{Precedence: 5, Associativity: parser.RightAssociative}
+	loose.Prefix["~"] = parser.Operator{Precedence: 1, Associativity: parser.RightAssociative}
+	loose.Prefix["-"] = parser.Operator{Precedence: 2}
+	loose.Postfix["?"] = parser.Operator{Precedence: 1}
+
+	cases := []struct {
+		o     parser.Options
+		extra []string
+	}{
+		{parser.DefaultOptions(), []string{"+", "-", "!", "$"}},
+		{dialect(), []string{"-", "~", "!", "/"}},
+		{loose, []string{"&", "?", "~", "-"}},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			r := rand.New(rand.NewSource(int64(i)))
+
+			for j := 0; j < 5000; j++ {
+				s := randomSource(r, c.o, c.extra)
+				climbing, shunting := parseBoth(c.o, s)
+
+				if climbing != shunting {
+					t.Fatalf("%q: expected %s but got %s", s, climbing, shunting)
+				}
+			}
+		})
+	}
+}
+
+func TestShuntingYardCases(t *testing.T) {
+	cases := []string{
+		"1 + 2 * 3 - 4",
+		"2 ^ 3 ^ 2",
+		"-(x + 1) * 2",
+		"(1 + 2)! * 3!!",
+		"((1)",
+		"(1))",
+		"1 2",
+		"(1 2)",
+		"1 +",
+		"1 $ 2",
+		"- -",
+		"-(1)",
+		"()",
+	}
+
+	for i, s := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			for _, o := range []parser.Options{parser.DefaultOptions(), dialect()} {
+				climbing, shunting := parseBoth(o, s)
+
+				if climbing != shunting {
+					t.Fatalf("expected %s but got %s", climbing, shunting)
+				}
+			}
+		})
+	}
+}
+
+func TestShuntingYardEquation(t *testing.T) {
+	o := dialect()
+	o.ShuntingYard = true
+
+	lhs, rhs, err := o.ParseEquationString("~x ** 2 = (1 + 2)!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lhs.String() != "((~x) ** 2)" || rhs.String() != "((1 + 2)!)" {
+		t.Fatalf("expected ((~x) ** 2) = ((1 + 2)!), got %s = %s", lhs, rhs)
+	}
+}
+
+func TestShuntingYardDepth(t *testing.T) {
+	unlimited := parser.DefaultOptions()
+	unlimited.Limits = parser.Limits{}
+	unlimited.ShuntingYard = true
+
+	n, err := unlimited.ParseString(strings.Repeat("(", 1000000) + "1" + strings.Repeat(")", 1000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.String() != "1" {
+		t.Fatalf("expected 1, got %s", n)
+	}
+
+	n, err = unlimited.ParseString(strings.Repeat("2 ^ ", 40000) + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var depth int
+
+	for b, ok := n.(ast.BinaryExpr); ok; b, ok = b.Right.(ast.BinaryExpr) {
+		depth++
+	}
+
+	if depth != 40000 {
+		t.Fatalf("expected 40000 levels, got %d", depth)
+	}
+
+	// The depth of the tree is still limited, even though parsing doesn't
+	// recurse.
+	o := parser.DefaultOptions()
+	o.ShuntingYard = true
+
+	_, err = o.ParseString(strings.Repeat("2 ^ ", 40000) + "2")
+
+	var l *parser.LimitError
+	if !errors.As(err, &l) || l.Limit != parser.DepthLimit || l.Pos != 3996 {
+		t.Fatalf("expected depth limit error at 3996, got %v", err)
+	}
+
+	_, err = o.ParseString(strings.Repeat("1 + ", 40000) + "1")
+
+	if !errors.As(err, &l) || l.Limit != parser.DepthLimit || l.Pos != 0 {
+		t.Fatalf("expected depth limit error at 0, got %v", err)
+	}
+}
@@ -0,0 +1,382 @@
+// Package poly implements polynomials in a single variable with exact
+// rational coefficients.
+package poly
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/token"
+	"math/big"
+)
+
+// MaxDegree is the largest degree of polynomial which FromNode will build, so
+// that expressions such as x^1000000000 or repeated products fail rather than
+// exhausting memory. Every intermediate polynomial is limited, not just the
+// result.
+const MaxDegree = 1000
+
+// ErrDivideByZero is returned when dividing by the zero polynomial.
+var ErrDivideByZero = errors.New("division by the zero polynomial")
+
+// Poly is a polynomial in the variable Var. Coef[i] holds the coefficient of
+// Var^i, and there are never any trailing zero coefficients, so the zero
+// polynomial has no coefficients at all.
+type Poly struct {
+	Var  string
+	Coef []*big.Rat
+}
+
+// New returns the polynomial in x with the provided coefficients, lowest
+// degree first.
+func New(x string, coef ...*big.Rat) Poly {
+	return Poly{Var: x, Coef: coef}.trim()
+}
+
+// Constant returns the constant polynomial c.
+func Constant(c *big.Rat) Poly {
+	return New("", c)
+}
+
+// trim removes any trailing zero coefficients.
+func (p Poly) trim() Poly {
+	for len(p.Coef) > 0 && p.Coef[len(p.Coef)-1].Sign() == 0 {
+		p.Coef = p.Coef[:len(p.Coef)-1]
+	}
+
+	return p
+}
+
+// Degree returns the degree of p, or -1 if p is the zero polynomial.
+func (p Poly) Degree() int {
+	return len(p.Coef) - 1
+}
+
+// coef returns the coefficient of Var^i, which may be beyond the degree.
+func (p Poly) coef(i int) *big.Rat {
+	if i < len(p.Coef) {
+		return p.Coef[i]
+	}
+
+	return new(big.Rat)
+}
+
+// lead returns the leading coefficient of p.
+func (p Poly) lead() *big.Rat {
+	return p.coef(p.Degree())
+}
+
+// variable returns the variable of p and q, preferring the variable of a
+// non-constant polynomial.
+func variable(p, q Poly) string {
+	if p.Var == "" || (p.Degree() < 1 && q.Var != "") {
+		return q.Var
+	}
+
+	return p.Var
+}
+
+// Add returns p + q.
+func (p Poly) Add(q Poly) Poly {
+	n := len(p.Coef)
+	if len(q.Coef) > n {
+		n = len(q.Coef)
+	}
+
+	coef := make([]*big.Rat, n)
+
+	for i := range coef {
+		coef[i] = new(big.Rat).Add(p.coef(i), q.coef(i))
+	}
+
+	return New(variable(p, q), coef...)
+}
+
+// Neg returns -p.
+func (p Poly) Neg() Poly {
+	coef := make([]*big.Rat, len(p.Coef))
+
+	for i, c := range p.Coef {
+		coef[i] = new(big.Rat).Neg(c)
+	}
+
+	return New(p.Var, coef...)
+}
+
+// Sub returns p - q.
+func (p Poly) Sub(q Poly) Poly {
+	return p.Add(q.Neg())
+}
+
+// Mul returns p * q.
+func (p Poly) Mul(q Poly) Poly {
+	if p.Degree() < 0 || q.Degree() < 0 {
+		return New(variable(p, q))
+	}
+
+	coef := make([]*big.Rat, len(p.Coef)+len(q.Coef)-1)
+
+	for i := range coef {
+		coef[i] = new(big.Rat)
+	}
+
+	for i, a := range p.Coef {
+		for j, b := range q.Coef {
+			coef[i+j].Add(coef[i+j], new(big.Rat).Mul(a, b))
+		}
+	}
+
+	return New(variable(p, q), coef...)
+}
+
+// Scale returns c * p.
+func (p Poly) Scale(c *big.Rat) Poly {
+	return p.Mul(Constant(c))
+}
+
+// Pow returns p^n.
+func (p Poly) Pow(n int) Poly {
+	result := New(p.Var, big.NewRat(1, 1))
+
+	// Square and multiply.
+	for base := p; n > 0; n /= 2 {
+		if n%2 == 1 {
+			result = result.Mul(base)
+		}
+
+		base = base.Mul(base)
+	}
+
+	return result
+}
+
+// DivMod returns the quotient and remainder of dividing p by q.
+func (p Poly) DivMod(q Poly) (Poly, Poly, error) {
+	if q.Degree() < 0 {
+		return Poly{}, Poly{}, ErrDivideByZero
+	}
+
+	x := variable(p, q)
+	quot := make([]*big.Rat, 0)
+	rem := New(x, p.Coef...)
+
+	if d := p.Degree() - q.Degree() + 1; d > 0 {
+		quot = make([]*big.Rat, d)
+
+		for i := range quot {
+			quot[i] = new(big.Rat)
+		}
+	}
+
+	// Long division, cancelling the leading term of the remainder each time.
+	for rem.Degree() >= q.Degree() {
+		shift := rem.Degree() - q.Degree()
+		c := new(big.Rat).Quo(rem.lead(), q.lead())
+		quot[shift] = c
+
+		term := make([]*big.Rat, shift+1)
+		for i := range term {
+			term[i] = new(big.Rat)
+		}
+		term[shift] = c
+
+		rem = rem.Sub(New(x, term...).Mul(q))
+	}
+
+	return New(x, quot...), rem, nil
+}
+
+// Monic returns p divided by its leading coefficient.
+func (p Poly) Monic() Poly {
+	if p.Degree() < 0 {
+		return p
+	}
+
+	return p.Scale(new(big.Rat).Inv(p.lead()))
+}
+
+// GCD returns the monic greatest common divisor of p and q.
+func GCD(p, q Poly) Poly {
+	for q.Degree() >= 0 {
+		_, r, _ := p.DivMod(q)
+		p, q = q, r
+	}
+
+	return p.Monic()
+}
+
+// FromNode converts n into a polynomial in x. Products and integer powers are
+// expanded, and division is only allowed by constants.
+func FromNode(n ast.Node, x string) (Poly, error) {
+	switch n := n.(type) {
+	case ast.Lit:
+		if n.Type == token.ConstantToken {
+			if n.Unsigned() != x {
+				return Poly{}, fmt.Errorf("%s is not a polynomial in %s: unknown constant %q at %d", n, x, n.Unsigned(), n.ValuePos)
+			}
+
+			p := New(x, new(big.Rat), big.NewRat(1, 1))
+
+			if n.Negative() {
+				return p.Neg(), nil
+			}
+
+			return p, nil
+		}
+
+		c, ok := new(big.Rat).SetString(n.Value)
+		if !ok {
+			return Poly{}, fmt.Errorf("invalid number %q at %d", n.Value, n.Pos())
+		}
+
+		return New(x, c), nil
+	case ast.BinaryExpr:
+		left, err := FromNode(n.Left, x)
+		if err != nil {
+			return Poly{}, err
+		}
+
+		right, err := FromNode(n.Right, x)
+		if err != nil {
+			return Poly{}, err
+		}
+
+		// Sums can't have a higher degree than their sides, but products add
+		// their degrees, so check before doing the work.
+		if n.Op == "+" || n.Op == "-" || n.Op == "*" {
+			degree := left.Degree()
+			if right.Degree() > degree {
+				degree = right.Degree()
+			}
+
+			if n.Op == "*" && left.Degree() >= 0 && right.Degree() >= 0 {
+				degree = left.Degree() + right.Degree()
+			}
+
+			if degree > MaxDegree {
+				return Poly{}, fmt.Errorf("%s has a degree larger than %d at %d", n, MaxDegree, n.OpPos)
+			}
+		}
+
+		switch n.Op {
+		case "+":
+			return left.Add(right), nil
+		case "-":
+			return left.Sub(right), nil
+		case "*":
+			return left.Mul(right), nil
+		case "/":
+			if right.Degree() > 0 {
+				return Poly{}, fmt.Errorf("%s is not a polynomial in %s: division by %s at %d", n, x, n.Right, n.OpPos)
+			}
+
+			if right.Degree() < 0 {
+				return Poly{}, fmt.Errorf("%s is not a polynomial in %s: division by zero at %d", n, x, n.OpPos)
+			}
+
+			return left.Scale(new(big.Rat).Inv(right.Coef[0])), nil
+		case "^":
+			// Only non-negative integer powers can be expanded.
+			e := right.coef(0)
+
+			if right.Degree() > 0 || !e.IsInt() || e.Sign() < 0 {
+				return Poly{}, fmt.Errorf("%s is not a polynomial in %s: %s is not a non-negative integer at %d", n, x, n.Right, n.OpPos)
+			}
+
+			if !e.Num().IsInt64() || e.Num().Int64() > MaxDegree {
+				return Poly{}, fmt.Errorf("%s has an exponent larger than %d at %d", n, MaxDegree, n.OpPos)
+			}
+
+			if int64(left.Degree())*e.Num().Int64() > MaxDegree {
+				return Poly{}, fmt.Errorf("%s has a degree larger than %d at %d", n, MaxDegree, n.OpPos)
+			}
+
+			return left.Pow(int(e.Num().Int64())), nil
+		default:
+			return Poly{}, fmt.Errorf("%s is not a polynomial in %s: unsupported operation %s at %d", n, x, n.Op, n.OpPos)
+		}
+	case ast.UnaryExpr:
+		if n.Postfix && n.Op == "%" {
+			p, err := FromNode(n.X, x)
+			if err != nil {
+				return Poly{}, err
+			}
+
+			return p.Scale(big.NewRat(1, 100)), nil
+		}
+
+		return Poly{}, fmt.Errorf("%s is not a polynomial in %s: unsupported operation %s at %d", n, x, n.Op, n.OpPos)
+	case ast.BadExpr:
+		return Poly{}, fmt.Errorf("bad expression at %d", n.From)
+	default:
+		return Poly{}, fmt.Errorf("unknown node %T", n)
+	}
+}
+
+// Node returns p as a tree, from the highest degree term down. Fractional
+// coefficients are written as divisions, so the tree is exact.
+func (p Poly) Node() ast.Node {
+	var result ast.Node
+
+	for i := p.Degree(); i >= 0; i-- {
+		c := p.Coef[i]
+
+		if c.Sign() == 0 {
+			continue
+		}
+
+		// Subtract negative terms, unless there's nothing to subtract from.
+		op := "+"
+		num := new(big.Int).Set(c.Num())
+
+		if result != nil && num.Sign() < 0 {
+			op = "-"
+			num.Neg(num)
+		}
+
+		var term ast.Node
+
+		if i > 0 {
+			term = ast.Lit{Type: token.ConstantToken, Value: p.Var}
+
+			if i > 1 {
+				term = ast.BinaryExpr{Left: term, Right: ast.NewInt(big.NewInt(int64(i)), 0), Op: "^"}
+			}
+
+			switch {
+			case num.IsInt64() && num.Int64() == 1:
+			case num.IsInt64() && num.Int64() == -1:
+				if l, ok := term.(ast.Lit); ok {
+					l.Value = "-" + l.Value
+					term = l
+				} else {
+					term = ast.BinaryExpr{Left: ast.NewInt(num, 0), Right: term, Op: "*"}
+				}
+			default:
+				term = ast.BinaryExpr{Left: ast.NewInt(num, 0), Right: term, Op: "*"}
+			}
+		} else {
+			term = ast.NewInt(num, 0)
+		}
+
+		if !c.IsInt() {
+			term = ast.BinaryExpr{Left: term, Right: ast.NewInt(c.Denom(), 0), Op: "/"}
+		}
+
+		if result == nil {
+			result = term
+		} else {
+			result = ast.BinaryExpr{Left: result, Right: term, Op: op}
+		}
+	}
+
+	if result == nil {
+		return ast.NewInt(new(big.Int), 0)
+	}
+
+	return result
+}
+
+func (p Poly) String() string {
+	return p.Node().String()
+}
@@ -0,0 +1,275 @@
+package poly_test
+
+import (
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/poly"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// parse parses s into a polynomial in x.
+func parse(t *testing.T, s string) poly.Poly {
+	t.Helper()
+
+	n, err := parser.ParseString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := poly.FromNode(n, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestFromNode(t *testing.T) {
+	cases := []struct {
+		s, expected string
+	}{
+		{"0", "0"},
+		{"3 - 5", "-2"},
+		{"x", "x"},
+		{"-x", "-x"},
+		{"x * x - 1", "((x ^ 2) - 1)"},
+		{"(x + 1) ^ 2", "(((x ^ 2) + (2 * x)) + 1)"},
+		{"(x - 1) * (x + 1) - x ^ 2", "-1"},
+		{"x / 2 + 0.25", "((x / 2) + (1 / 4))"},
+		{"-x ^ 3 / -3", "((x ^ 3) / 3)"},
+		{"(2 * x - 1) ^ 3", "((((8 * (x ^ 3)) - (12 * (x ^ 2))) + (6 * x)) - 1)"},
+		{"-2 * x ^ 2 + x", "((-2 * (x ^ 2)) + x)"},
+		{"x ^ 1000 * 0 + x * x ^ 999 - x ^ 500 * x ^ 500", "0"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			p := parse(t, c.s)
+
+			if p.String() != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, p)
+			}
+
+			// The printed tree should evaluate to the same as the original.
+			original, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, x := range []float64{-2, 0.5, 3} {
+				constants := map[string]float64{"x": x}
+
+				expected, err := evaluator.Evaluate(original, constants)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				actual, err := evaluator.Evaluate(p.Node(), constants)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if math.Abs(expected-actual) > 1e-12*math.Max(1, math.Abs(expected)) {
+					t.Errorf("x=%v: expected %v but got %v", x, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestFromNodeErrors(t *testing.T) {
+	cases := []string{
+		"x * y",
+		"1 / x",
+		"x / (x - x)",
+		"x ^ 0.5",
+		"x ^ -1",
+		"x ^ x",
+		"x ^ 1001",
+		"(x ^ 100) ^ 11",
+		"x ^ 1000 * x",
+		"x ^ 600 * x ^ 600",
+		"x ^ 1000 * x ^ 1000 * x ^ 1000",
+		"(x ^ 2 + 1) * x ^ 999",
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if p, err := poly.FromNode(n, "x"); err == nil {
+				t.Fatalf("expected an error but got %s", p)
+			}
+		})
+	}
+}
+
+func TestDivModGCD(t *testing.T) {
+	p := parse(t, "x ^ 3 - 2 * x ^ 2 - 4")
+	q := parse(t, "x - 3")
+
+	quot, rem, err := p.DivMod(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := quot.String(); s != "(((x ^ 2) + x) + 3)" {
+		t.Errorf("unexpected quotient %s", s)
+	}
+
+	if s := rem.String(); s != "5" {
+		t.Errorf("unexpected remainder %s", s)
+	}
+
+	if _, _, err := p.DivMod(poly.New("x")); err != poly.ErrDivideByZero {
+		t.Errorf("expected division by zero but got %v", err)
+	}
+
+	a := parse(t, "(x - 1) * (x + 2) ^ 2 * 3")
+	b := parse(t, "(x + 2) * (x - 5) / 2")
+
+	if s := poly.GCD(a, b).String(); s != "(x + 2)" {
+		t.Errorf("unexpected GCD %s", s)
+	}
+}
+
+func TestFactor(t *testing.T) {
+	p := parse(t, "2 * x * (x - 1) ^ 2 * (2 * x + 3) * (x ^ 2 + 1)")
+
+	lead, factors := p.Factor()
+
+	if lead.Cmp(big.NewRat(4, 1)) != 0 {
+		t.Errorf("expected leading coefficient 4 but got %s", lead)
+	}
+
+	var s []string
+	for _, f := range factors {
+		s = append(s, f.String())
+	}
+
+	expected := []string{"x", "(x - 1)", "(x - 1)", "(x + (3 / 2))", "((x ^ 2) + 1)"}
+
+	if len(s) != len(expected) {
+		t.Fatalf("expected factors %q but got %q", expected, s)
+	}
+
+	for i := range s {
+		if s[i] != expected[i] {
+			t.Fatalf("expected factors %q but got %q", expected, s)
+		}
+	}
+}
+
+// TestFactorManyCandidates checks that coefficients with many divisors don't
+// make the search for rational roots take too long.
+func TestFactorManyCandidates(t *testing.T) {
+	cases := []struct {
+		s       string
+		factors int
+	}{
+		{"x ^ 2 + x / 2756205443 + 963761198400 / 2756205443", 1},
+		{"x ^ 30 + x / 2756205443 + 963761198400 / 2756205443", 1},
+		{"(x - 720720) * (x ^ 2 + 720720)", 2},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			p := parse(t, c.s)
+
+			start := time.Now()
+			_, factors := p.Factor()
+
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("expected factoring to be quick but it took %s", elapsed)
+			}
+
+			if len(factors) != c.factors {
+				t.Fatalf("expected %d factors but got %v", c.factors, factors)
+			}
+
+			// The roots are still found numerically.
+			roots, err := p.Roots()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(roots) != p.Degree() {
+				t.Fatalf("expected %d roots but got %v", p.Degree(), roots)
+			}
+		})
+	}
+}
+
+func TestRoots(t *testing.T) {
+	sqrt2 := math.Sqrt2
+	cbrt2 := math.Cbrt(2)
+	fourth2 := math.Pow(2, 0.25)
+
+	cases := []struct {
+		s     string
+		roots []complex128
+	}{
+		{"5", nil},
+		{"2 * x - 1", []complex128{0.5}},
+		{"x ^ 2 - 2", []complex128{complex(-sqrt2, 0), complex(sqrt2, 0)}},
+		{"x ^ 2 - 2 * x + 1", []complex128{1, 1}},
+		{"x ^ 2 + 2 * x + 5", []complex128{complex(-1, -2), complex(-1, 2)}},
+		{"x ^ 3 - 2", []complex128{complex(-cbrt2/2, -cbrt2*math.Sqrt(3)/2), complex(-cbrt2/2, cbrt2*math.Sqrt(3)/2), complex(cbrt2, 0)}},
+		{"x ^ 3 - 3 * x + 1", []complex128{
+			complex(2*math.Cos(8*math.Pi/9), 0),
+			complex(2*math.Cos(4*math.Pi/9), 0),
+			complex(2*math.Cos(2*math.Pi/9), 0),
+		}},
+		{"(x - 0.5) ^ 3", []complex128{0.5, 0.5, 0.5}},
+		{"(x - 2) ^ 2 * (x + 1)", []complex128{-1, 2, 2}},
+		{"x ^ 3 + x - 1", nil},
+		{"x ^ 4 - 10 * x ^ 2 + 9", []complex128{-3, -1, 1, 3}},
+		{"x ^ 4 - 2", []complex128{complex(-fourth2, 0), complex(0, -fourth2), complex(0, fourth2), complex(fourth2, 0)}},
+		{"x ^ 5 - x - 1", nil},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			p := parse(t, c.s)
+
+			roots, err := p.Roots()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(roots) != p.Degree() && p.Degree() > 0 {
+				t.Fatalf("expected %d roots but got %v", p.Degree(), roots)
+			}
+
+			// Where the roots aren't listed, just check that they are roots.
+			for j, r := range roots {
+				if c.roots != nil && cmplx.Abs(r-c.roots[j]) > 1e-12 {
+					t.Errorf("expected roots %v but got %v", c.roots, roots)
+					break
+				}
+
+				var v complex128
+				for k := p.Degree(); k >= 0; k-- {
+					f, _ := p.Coef[k].Float64()
+					v = v*r + complex(f, 0)
+				}
+
+				if cmplx.Abs(v) > 1e-9 {
+					t.Errorf("p(%v) = %v", r, v)
+				}
+			}
+		})
+	}
+
+	if _, err := poly.New("x").Roots(); err != poly.ErrZero {
+		t.Fatalf("expected ErrZero but got %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+package poly
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"sort"
+)
+
+// ErrZero is returned when finding the roots of the zero polynomial.
+var ErrZero = errors.New("the zero polynomial has infinitely many roots")
+
+// maxDivisor is the largest coefficient which Factor will search the divisors
+// of for rational roots.
+const maxDivisor = 1e12
+
+// maxCandidateTerms limits the search for rational roots to polynomials whose
+// number of candidates times number of coefficients is at most this, as each
+// candidate is checked against every coefficient. Anything larger is left for
+// the numeric root finder instead.
+const maxCandidateTerms = 100000
+
+// eval returns p(x) exactly.
+func (p Poly) eval(x *big.Rat) *big.Rat {
+	v := new(big.Rat)
+
+	// Horner's method.
+	for i := p.Degree(); i >= 0; i-- {
+		v.Mul(v, x)
+		v.Add(v, p.Coef[i])
+	}
+
+	return v
+}
+
+// divisors returns the positive divisors of n, or false if n is too large to
+// search.
+func divisors(n *big.Int) ([]int64, bool) {
+	if n.CmpAbs(big.NewInt(maxDivisor)) > 0 {
+		return nil, false
+	}
+
+	v := new(big.Int).Abs(n).Int64()
+
+	var ds []int64
+
+	for i := int64(1); i*i <= v; i++ {
+		if v%i == 0 {
+			ds = append(ds, i)
+
+			if i*i != v {
+				ds = append(ds, v/i)
+			}
+		}
+	}
+
+	return ds, true
+}
+
+// rationalCandidates returns the candidates for the rational roots of p using
+// the rational root theorem.
+func rationalCandidates(p Poly) []*big.Rat {
+	// Scale to integer coefficients by the lowest common multiple of the
+	// denominators.
+	lcm := big.NewInt(1)
+
+	for _, c := range p.Coef {
+		gcd := new(big.Int).GCD(nil, nil, lcm, c.Denom())
+		lcm.Mul(lcm, new(big.Int).Quo(c.Denom(), gcd))
+	}
+
+	scale := new(big.Rat).SetInt(lcm)
+	constant := new(big.Rat).Mul(p.Coef[0], scale)
+	lead := new(big.Rat).Mul(p.lead(), scale)
+
+	// Every rational root is a divisor of the constant term over a divisor of
+	// the leading coefficient.
+	nums, ok := divisors(constant.Num())
+	if !ok {
+		return nil
+	}
+
+	dens, ok := divisors(lead.Num())
+	if !ok {
+		return nil
+	}
+
+	if len(nums)*len(dens)*2*len(p.Coef) > maxCandidateTerms {
+		return nil
+	}
+
+	var candidates []*big.Rat
+	seen := make(map[string]bool)
+
+	for _, num := range nums {
+		for _, den := range dens {
+			for _, sign := range []int64{1, -1} {
+				r := big.NewRat(sign*num, den)
+
+				if !seen[r.String()] {
+					seen[r.String()] = true
+					candidates = append(candidates, r)
+				}
+			}
+		}
+	}
+
+	return candidates
+}
+
+// Factor returns the leading coefficient of p along with monic factors whose
+// product is p divided by it. A factor x - r is split out for each rational
+// root r, repeated for repeated roots, and whatever remains is returned as a
+// single factor.
+func (p Poly) Factor() (*big.Rat, []Poly) {
+	if p.Degree() < 1 {
+		return new(big.Rat).Set(p.coef(0)), nil
+	}
+
+	lead := new(big.Rat).Set(p.lead())
+	m := p.Monic()
+
+	var factors []Poly
+
+	// Split out any roots at zero first, as they'd hide the constant term.
+	for m.Degree() > 0 && m.Coef[0].Sign() == 0 {
+		factors = append(factors, New(p.Var, new(big.Rat), big.NewRat(1, 1)))
+		m = New(p.Var, m.Coef[1:]...)
+	}
+
+	if m.Degree() > 0 {
+		// Roots of the quotients are roots of m, so the candidates only need
+		// to be found once.
+		for _, r := range rationalCandidates(m) {
+			for m.Degree() > 0 && m.eval(r).Sign() == 0 {
+				factor := New(p.Var, new(big.Rat).Neg(r), big.NewRat(1, 1))
+				factors = append(factors, factor)
+				m, _, _ = m.DivMod(factor)
+			}
+		}
+	}
+
+	if m.Degree() > 0 {
+		factors = append(factors, m)
+	}
+
+	return lead, factors
+}
+
+// float returns r as a float64.
+func float(r *big.Rat) float64 {
+	f, _ := r.Float64()
+	return f
+}
+
+// quadratic returns the roots of the monic x^2 + bx + c.
+func quadratic(b, c *big.Rat) []complex128 {
+	// disc = b^2 - 4c
+	disc := new(big.Rat).Mul(b, b)
+	disc.Sub(disc, new(big.Rat).Mul(big.NewRat(4, 1), c))
+
+	fb, fc := float(b), float(c)
+
+	switch disc.Sign() {
+	case 0:
+		r := complex(float(new(big.Rat).Quo(b, big.NewRat(-2, 1))), 0)
+		return []complex128{r, r}
+	case 1:
+		// Avoid cancellation by only adding numbers of the same sign.
+		s := math.Sqrt(float(disc))
+		q := -(fb + math.Copysign(s, fb)) / 2
+		return []complex128{complex(q, 0), complex(fc/q, 0)}
+	default:
+		im := math.Sqrt(-float(disc)) / 2
+		return []complex128{complex(-fb/2, -im), complex(-fb/2, im)}
+	}
+}
+
+// cubic returns the roots of the monic x^3 + bx^2 + cx + d.
+func cubic(b, c, d *big.Rat) []complex128 {
+	rat := func(n int64) *big.Rat {
+		return big.NewRat(n, 1)
+	}
+
+	mul := func(rs ...*big.Rat) *big.Rat {
+		v := rat(1)
+		for _, r := range rs {
+			v.Mul(v, r)
+		}
+		return v
+	}
+
+	// disc = 18bcd - 4b^3d + b^2c^2 - 4c^3 - 27d^2
+	disc := mul(rat(18), b, c, d)
+	disc.Sub(disc, mul(rat(4), b, b, b, d))
+	disc.Add(disc, mul(b, b, c, c))
+	disc.Sub(disc, mul(rat(4), c, c, c))
+	disc.Sub(disc, mul(rat(27), d, d))
+
+	// disc0 = b^2 - 3c
+	disc0 := new(big.Rat).Sub(mul(b, b), mul(rat(3), c))
+
+	if disc.Sign() == 0 {
+		// A triple root.
+		if disc0.Sign() == 0 {
+			r := complex(float(new(big.Rat).Quo(b, rat(-3))), 0)
+			return []complex128{r, r, r}
+		}
+
+		// A double root, (9d - bc) / (2 disc0), and a simple root,
+		// (4bc - 9d - b^3) / disc0.
+		double := new(big.Rat).Sub(mul(rat(9), d), mul(b, c))
+		double.Quo(double, mul(rat(2), disc0))
+
+		simple := new(big.Rat).Sub(mul(rat(4), b, c), mul(rat(9), d))
+		simple.Sub(simple, mul(b, b, b))
+		simple.Quo(simple, disc0)
+
+		return []complex128{complex(float(double), 0), complex(float(double), 0), complex(float(simple), 0)}
+	}
+
+	// Substitute x = t - b/3 to get the depressed cubic t^3 + pt + q.
+	fb := float(b)
+	shift := -fb / 3
+	p := float(c) - fb*fb/3
+	q := 2*fb*fb*fb/27 - fb*float(c)/3 + float(d)
+
+	// Three distinct real roots, using the trigonometric method.
+	if disc.Sign() > 0 {
+		m := 2 * math.Sqrt(-p/3)
+		theta := math.Acos(math.Max(-1, math.Min(1, 3*q/(p*m)))) / 3
+
+		roots := make([]complex128, 3)
+		for k := range roots {
+			roots[k] = complex(m*math.Cos(theta-2*math.Pi*float64(k)/3)+shift, 0)
+		}
+
+		return roots
+	}
+
+	// One real root and a complex conjugate pair, using Cardano's formula.
+	s := math.Sqrt(q*q/4 + p*p*p/27)
+	t := math.Cbrt(-q/2+s) + math.Cbrt(-q/2-s)
+
+	// The others are the roots of t^2 + t1 t + (t1^2 + p).
+	im := math.Sqrt(3*t*t+4*p) / 2
+
+	return []complex128{
+		complex(t+shift, 0),
+		complex(-t/2+shift, -im),
+		complex(-t/2+shift, im),
+	}
+}
+
+// numeric returns the roots of the monic p using the Durand-Kerner method.
+func numeric(p Poly) []complex128 {
+	n := p.Degree()
+	coef := make([]complex128, len(p.Coef))
+
+	for i, c := range p.Coef {
+		coef[i] = complex(float(c), 0)
+	}
+
+	eval := func(x complex128) complex128 {
+		var v complex128
+		for i := n; i >= 0; i-- {
+			v = v*x + coef[i]
+		}
+		return v
+	}
+
+	// Start from distinct points which aren't symmetric about the real axis.
+	roots := make([]complex128, n)
+	for i := range roots {
+		roots[i] = cmplx.Pow(complex(0.4, 0.9), complex(float64(i), 0))
+	}
+
+	for iteration := 0; iteration < 1000; iteration++ {
+		var change float64
+
+		for i, r := range roots {
+			denom := complex(1, 0)
+			for j, s := range roots {
+				if i != j {
+					denom *= r - s
+				}
+			}
+
+			next := r - eval(r)/denom
+			change = math.Max(change, cmplx.Abs(next-r))
+			roots[i] = next
+		}
+
+		if change < 1e-15 {
+			break
+		}
+	}
+
+	// Tidy up the imaginary parts left behind on real roots.
+	for i, r := range roots {
+		if math.Abs(imag(r)) <= 1e-10*math.Max(1, cmplx.Abs(r)) {
+			roots[i] = complex(real(r), 0)
+		}
+	}
+
+	return roots
+}
+
+// Roots returns the roots of p, with repeated roots repeated, sorted by their
+// real and then imaginary parts. Rational roots are found exactly, as are the
+// roots of what remains if it has a degree of 2 or 3. The roots of anything
+// with a higher degree are found numerically.
+func (p Poly) Roots() ([]complex128, error) {
+	if p.Degree() < 0 {
+		return nil, ErrZero
+	}
+
+	_, factors := p.Factor()
+
+	var roots []complex128
+
+	for _, f := range factors {
+		switch f.Degree() {
+		case 1:
+			roots = append(roots, complex(float(new(big.Rat).Neg(f.Coef[0])), 0))
+		case 2:
+			roots = append(roots, quadratic(f.Coef[1], f.Coef[0])...)
+		case 3:
+			roots = append(roots, cubic(f.Coef[2], f.Coef[1], f.Coef[0])...)
+		default:
+			roots = append(roots, numeric(f)...)
+		}
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		if real(roots[i]) != real(roots[j]) {
+			return real(roots[i]) < real(roots[j])
+		}
+
+		return imag(roots[i]) < imag(roots[j])
+	})
+
+	return roots, nil
+}
@@ -0,0 +1,351 @@
+package simplify
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"math"
+)
+
+// ratio is a coefficient stored as a fraction, so that collecting x/3 + x/3
+// gives 2*x/3 rather than 0.6666666666666666*x.
+type ratio struct {
+	num, den float64
+}
+
+// maxExact is the largest integer below which every integer can be stored
+// exactly in a float64.
+const maxExact = 1 << 53
+
+// isInteger returns whether v is an integer which can be stored exactly.
+func isInteger(v float64) bool {
+	return v == math.Trunc(v) && math.Abs(v) < maxExact
+}
+
+// reduce returns r in its lowest terms, with a positive denominator.
+func (r ratio) reduce() ratio {
+	if r.den < 0 {
+		r.num, r.den = -r.num, -r.den
+	}
+
+	// Only integer fractions can be reduced exactly, so anything else is
+	// stored as a plain number.
+	if !isInteger(r.num) || !isInteger(r.den) {
+		return ratio{r.num / r.den, 1}
+	}
+
+	a, b := math.Abs(r.num), r.den
+	for b != 0 {
+		a, b = b, math.Mod(a, b)
+	}
+
+	if a > 1 {
+		r.num, r.den = r.num/a, r.den/a
+	}
+
+	return r
+}
+
+func (r ratio) add(s ratio) ratio {
+	return ratio{r.num*s.den + s.num*r.den, r.den * s.den}.reduce()
+}
+
+func (r ratio) mul(s ratio) ratio {
+	return ratio{r.num * s.num, r.den * s.den}.reduce()
+}
+
+// valid returns whether r can be written out as literals.
+func (r ratio) valid() bool {
+	return !math.IsNaN(r.num) && !math.IsInf(r.num, 0) &&
+		!math.IsNaN(r.den) && !math.IsInf(r.den, 0) && r.den != 0
+}
+
+// term is a coefficient multiplied by a body. Constant terms have a nil body.
+type term struct {
+	coef ratio
+	body ast.Node
+}
+
+// split splits n into a coefficient and a body.
+func split(n ast.Node) (ratio, ast.Node) {
+	if v, ok := number(n); ok {
+		return ratio{v, 1}, nil
+	}
+
+	switch n := n.(type) {
+	case ast.Lit:
+		// Treat -x as -1*x so that it can be collected with x.
+		if n.Negative() {
+			n.Value = n.Unsigned()
+			return ratio{-1, 1}, n
+		}
+	case ast.BinaryExpr:
+		if v, ok := number(n.Left); ok && n.Op == "*" {
+			c, body := split(n.Right)
+			return c.mul(ratio{v, 1}), body
+		}
+
+		if v, ok := number(n.Right); ok && n.Op == "/" {
+			c, body := split(n.Left)
+			return c.mul(ratio{1, v}), body
+		}
+	}
+
+	return ratio{1, 1}, n
+}
+
+// same returns whether a and b are the same body.
+func same(a, b ast.Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.String() == b.String() && !bad(a)
+}
+
+// bad returns whether n contains a BadExpr. Bad expressions all look the same,
+// but stand in for different things, so they're never like each other.
+func bad(n ast.Node) bool {
+	var found bool
+
+	ast.Inspect(n, func(n ast.Node) bool {
+		if _, ok := n.(ast.BadExpr); ok {
+			found = true
+		}
+
+		return !found
+	})
+
+	return found
+}
+
+// addTerms adds the terms of the sum n, multiplied by sign, to ts.
+func addTerms(ts []term, n ast.Node, sign float64) []term {
+	if b, ok := n.(ast.BinaryExpr); ok && (b.Op == "+" || b.Op == "-") {
+		ts = addTerms(ts, b.Left, sign)
+
+		if b.Op == "-" {
+			sign = -sign
+		}
+
+		return addTerms(ts, b.Right, sign)
+	}
+
+	c, body := split(n)
+	c = c.mul(ratio{sign, 1})
+
+	// Add the coefficient onto an existing like term if there is one.
+	for i := range ts {
+		if same(ts[i].body, body) {
+			ts[i].coef = ts[i].coef.add(c)
+			return ts
+		}
+	}
+
+	return append(ts, term{c, body})
+}
+
+// scale returns c*body, where c is positive unless body is the first term.
+func scale(c ratio, body ast.Node, pos, opPos int) (ast.Node, bool) {
+	num, ok := ast.NewNumberAt(c.num, pos)
+	if !ok {
+		return nil, false
+	}
+
+	var n ast.Node = num
+
+	switch {
+	case body == nil:
+		// Constant terms are just the coefficient.
+	case c.num == 1:
+		n = body
+	case c.num == -1:
+		n = ast.Negate(body, opPos)
+	default:
+		n = ast.BinaryExpr{Left: num, Right: body, Op: "*", OpPos: opPos}
+	}
+
+	if c.den == 1 {
+		return n, true
+	}
+
+	den, ok := ast.NewNumberAt(c.den, pos)
+	if !ok {
+		return nil, false
+	}
+
+	return ast.BinaryExpr{Left: n, Right: den, Op: "/", OpPos: opPos}, true
+}
+
+// collectTerms collects the like terms of the sum b.
+func collectTerms(b ast.BinaryExpr) (ast.Node, bool) {
+	ts := addTerms(nil, b, 1)
+
+	// Keep the constant term for the end.
+	constant := ratio{0, 1}
+	var terms []term
+
+	for _, t := range ts {
+		if !t.coef.valid() {
+			return nil, false
+		}
+
+		if t.body == nil {
+			constant = t.coef
+		} else if t.coef.num != 0 {
+			terms = append(terms, t)
+		}
+	}
+
+	if constant.num != 0 || len(terms) == 0 {
+		terms = append(terms, term{constant, nil})
+	}
+
+	var result ast.Node
+
+	for _, t := range terms {
+		op := "+"
+
+		// Subtract negative terms, unless there's nothing to subtract from.
+		if result != nil && t.coef.num < 0 {
+			op = "-"
+			t.coef.num = -t.coef.num
+		}
+
+		n, ok := scale(t.coef, t.body, b.Pos(), b.OpPos)
+		if !ok {
+			return nil, false
+		}
+
+		if result == nil {
+			result = n
+		} else {
+			result = ast.BinaryExpr{Left: result, Right: n, Op: op, OpPos: b.OpPos}
+		}
+	}
+
+	return result, true
+}
+
+// factor is a base raised to an exponent.
+type factor struct {
+	base ast.Node
+	exp  float64
+}
+
+// mulFactors multiplies the coefficient and factors of the product n, raised
+// to exp, into c and fs.
+func mulFactors(c ratio, fs []factor, n ast.Node, exp float64) (ratio, []factor) {
+	if v, ok := number(n); ok {
+		if exp < 0 {
+			return c.mul(ratio{1, v}), fs
+		}
+
+		return c.mul(ratio{v, 1}), fs
+	}
+
+	var base ast.Node = n
+
+	switch n := n.(type) {
+	case ast.Lit:
+		// Treat -x as -1*x so that it can be collected with x.
+		if n.Negative() {
+			n.Value = n.Unsigned()
+			c = c.mul(ratio{-1, 1})
+			base = n
+		}
+	case ast.BinaryExpr:
+		switch n.Op {
+		case "*":
+			c, fs = mulFactors(c, fs, n.Left, exp)
+			return mulFactors(c, fs, n.Right, exp)
+		case "/":
+			c, fs = mulFactors(c, fs, n.Left, exp)
+			return mulFactors(c, fs, n.Right, -exp)
+		case "^":
+			if v, ok := number(n.Right); ok {
+				base = n.Left
+				exp *= v
+			}
+		}
+	}
+
+	// Add the exponent onto an existing like factor if there is one.
+	for i := range fs {
+		if same(fs[i].base, base) {
+			fs[i].exp += exp
+			return c, fs
+		}
+	}
+
+	return c, append(fs, factor{base, exp})
+}
+
+// collectFactors collects the like factors of the product b.
+func collectFactors(b ast.BinaryExpr) (ast.Node, bool) {
+	c, fs := mulFactors(ratio{1, 1}, nil, b, 1)
+
+	if !c.valid() {
+		return nil, false
+	}
+
+	if c.num == 0 {
+		return ast.NewNumberAt(0, b.Pos())
+	}
+
+	// Split the factors into the numerator and denominator.
+	var num, den ast.Node
+
+	for _, f := range fs {
+		exp := f.exp
+		product := &num
+
+		if exp == 0 {
+			continue
+		} else if exp < 0 {
+			exp = -exp
+			product = &den
+		}
+
+		n := f.base
+
+		if exp != 1 {
+			e, ok := ast.NewNumberAt(exp, b.Pos())
+			if !ok {
+				return nil, false
+			}
+
+			n = ast.BinaryExpr{Left: n, Right: e, Op: "^", OpPos: b.OpPos}
+		}
+
+		if *product == nil {
+			*product = n
+		} else {
+			*product = ast.BinaryExpr{Left: *product, Right: n, Op: "*", OpPos: b.OpPos}
+		}
+	}
+
+	// The numerator carries the coefficient, and the denominator its
+	// denominator.
+	n, ok := scale(ratio{c.num, 1}, num, b.Pos(), b.OpPos)
+	if !ok {
+		return nil, false
+	}
+
+	if c.den != 1 {
+		d, ok := ast.NewNumberAt(c.den, b.Pos())
+		if !ok {
+			return nil, false
+		}
+
+		if den == nil {
+			den = d
+		} else {
+			den = ast.BinaryExpr{Left: d, Right: den, Op: "*", OpPos: b.OpPos}
+		}
+	}
+
+	if den == nil {
+		return n, true
+	}
+
+	return ast.BinaryExpr{Left: n, Right: den, Op: "/", OpPos: b.OpPos}, true
+}
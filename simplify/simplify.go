@@ -0,0 +1,220 @@
+// Package simplify rewrites syntax trees into simpler, equivalent trees.
+package simplify
+
+import (
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/ast/astutil"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/token"
+	"strconv"
+)
+
+// Mode controls which rewrites Simplify is allowed to make.
+type Mode int
+
+const (
+	// Float only makes rewrites which evaluate to the same result as the
+	// original tree for every input, including infinities and NaN. Only the
+	// sign of a zero result may differ.
+	Float Mode = iota
+
+	// Algebraic makes every rewrite which holds for real numbers, such as
+	// replacing 0*x with 0, and collects like terms. The result may differ
+	// from the original tree for infinities, NaN and through rounding.
+	Algebraic
+)
+
+// Simplify returns a simplified copy of n. Constant sub-trees are folded and
+// identities such as x*1 and x^0 are removed. The operators are assumed to
+// have their default meanings, so use SimplifyEnv for other dialects.
+func Simplify(n ast.Node, mode Mode) ast.Node {
+	env := evaluator.NewEnv(nil)
+
+	// Simplify from the bottom up so that each operation sees simplified
+	// operands.
+	return astutil.Apply(n, nil, func(c *astutil.Cursor) bool {
+		switch n := c.Node().(type) {
+		case ast.BinaryExpr:
+			c.Replace(simplifyBinary(n, mode, env))
+		case ast.UnaryExpr:
+			if l, ok := foldUnary(n, env); ok {
+				c.Replace(l)
+			}
+		}
+
+		return true
+	})
+}
+
+// SimplifyEnv returns a copy of n with its constant sub-trees folded using the
+// operators in env. The other rewrites made by Simplify assume the default
+// operators, so they aren't made here.
+func SimplifyEnv(n ast.Node, env evaluator.Env) ast.Node {
+	return astutil.Apply(n, nil, func(c *astutil.Cursor) bool {
+		var l ast.Node
+		var ok bool
+
+		switch n := c.Node().(type) {
+		case ast.BinaryExpr:
+			l, ok = fold(n, env)
+		case ast.UnaryExpr:
+			l, ok = foldUnary(n, env)
+		}
+
+		if ok {
+			c.Replace(l)
+		}
+
+		return true
+	})
+}
+
+// simplifyBinary simplifies a binary expression with simplified operands.
+func simplifyBinary(b ast.BinaryExpr, mode Mode, env evaluator.Env) ast.Node {
+	if n, ok := fold(b, env); ok {
+		return n
+	}
+
+	if n, ok := identity(b, mode); ok {
+		return n
+	}
+
+	if mode == Algebraic {
+		var n ast.Node
+		var ok bool
+
+		switch b.Op {
+		case "+", "-":
+			n, ok = collectTerms(b)
+		case "*", "/":
+			n, ok = collectFactors(b)
+		}
+
+		if ok {
+			return n
+		}
+	}
+
+	return b
+}
+
+// number returns the value of n if it is a number literal.
+func number(n ast.Node) (float64, bool) {
+	l, ok := n.(ast.Lit)
+	if !ok || l.Type != token.NumberToken {
+		return 0, false
+	}
+
+	v, err := strconv.ParseFloat(l.Value, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// foldUnary evaluates unary operations on a number in env.
+func foldUnary(u ast.UnaryExpr, env evaluator.Env) (ast.Node, bool) {
+	if _, ok := number(u.X); !ok {
+		return nil, false
+	}
+
+	v, err := evaluator.EvaluateEnv(u, env)
+	if err != nil {
+		return nil, false
+	}
+
+	return ast.NewNumberAt(v, u.Pos())
+}
+
+// fold evaluates operations on two numbers in env.
+func fold(b ast.BinaryExpr, env evaluator.Env) (ast.Node, bool) {
+	_, leftOk := number(b.Left)
+	_, rightOk := number(b.Right)
+
+	if !leftOk || !rightOk {
+		return nil, false
+	}
+
+	v, err := evaluator.EvaluateEnv(b, env)
+	if err != nil {
+		return nil, false
+	}
+
+	// Results such as 1/0 can't be written as a literal.
+	return ast.NewNumberAt(v, b.Pos())
+}
+
+// identity removes operations which have no effect, such as x+0.
+func identity(b ast.BinaryExpr, mode Mode) (ast.Node, bool) {
+	left, leftOk := number(b.Left)
+	right, rightOk := number(b.Right)
+
+	isLeft := func(v float64) bool {
+		return leftOk && left == v
+	}
+
+	isRight := func(v float64) bool {
+		return rightOk && right == v
+	}
+
+	switch b.Op {
+	case "+":
+		if isLeft(0) {
+			return b.Right, true
+		}
+
+		if isRight(0) {
+			return b.Left, true
+		}
+	case "-":
+		if isRight(0) {
+			return b.Left, true
+		}
+
+		if isLeft(0) {
+			return ast.Negate(b.Right, b.OpPos), true
+		}
+	case "*":
+		if isLeft(1) {
+			return b.Right, true
+		}
+
+		if isRight(1) {
+			return b.Left, true
+		}
+
+		// Negating a literal is simpler than multiplying it.
+		if _, ok := b.Right.(ast.Lit); ok && isLeft(-1) {
+			return ast.Negate(b.Right, b.OpPos), true
+		}
+
+		if _, ok := b.Left.(ast.Lit); ok && isRight(-1) {
+			return ast.Negate(b.Left, b.OpPos), true
+		}
+
+		// 0*Inf and 0*NaN are both NaN, so this only holds for real numbers.
+		if mode == Algebraic && (isLeft(0) || isRight(0)) {
+			return ast.NewNumberAt(0, b.Pos())
+		}
+	case "/":
+		if isRight(1) {
+			return b.Left, true
+		}
+
+		if mode == Algebraic && isLeft(0) {
+			return ast.NewNumberAt(0, b.Pos())
+		}
+	case "^":
+		if isRight(1) {
+			return b.Left, true
+		}
+
+		// math.Pow returns 1 for these even if the other operand is NaN.
+		if isRight(0) || isLeft(1) {
+			return ast.NewNumberAt(1, b.Pos())
+		}
+	}
+
+	return nil, false
+}
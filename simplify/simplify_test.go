@@ -0,0 +1,144 @@
+package simplify_test
+
+import (
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/simplify"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	cases := []struct {
+		s        string
+		mode     simplify.Mode
+		expected string
+	}{
+		{"1 + 2 * 3", simplify.Float, "7"},
+		{"x * (2 ^ 3 - 7)", simplify.Float, "x"},
+		{"x * 3! + 50%", simplify.Float, "((x * 6) + 0.5)"},
+		{"x! * 1", simplify.Float, "(x!)"},
+		{"(x + 0) * 1 - 0", simplify.Float, "x"},
+		{"0 + x / 1", simplify.Float, "x"},
+		{"x ^ 1 + y ^ 0", simplify.Float, "(x + 1)"},
+		{"1 ^ x", simplify.Float, "1"},
+		{"0 - x", simplify.Float, "-x"},
+		{"-1 * -x", simplify.Float, "x"},
+		{"0 * x", simplify.Float, "(0 * x)"},
+		{"x * (3 - 3)", simplify.Float, "(x * 0)"},
+		{"0 / x", simplify.Float, "(0 / x)"},
+		{"x - x", simplify.Float, "(x - x)"},
+		{"x + x", simplify.Float, "(x + x)"},
+		{"1 / 0", simplify.Float, "(1 / 0)"},
+		{"2 * 3 * x", simplify.Float, "(6 * x)"},
+		{"2 * x * 3", simplify.Float, "((2 * x) * 3)"},
+		{"0 * x", simplify.Algebraic, "0"},
+		{"x * (3 - 3)", simplify.Algebraic, "0"},
+		{"0 / x", simplify.Algebraic, "0"},
+		{"x - x", simplify.Algebraic, "0"},
+		{"x / x", simplify.Algebraic, "1"},
+		{"x + x", simplify.Algebraic, "(2 * x)"},
+		{"2 * x * 3", simplify.Algebraic, "(6 * x)"},
+		{"1 + x + 2 * x - 3", simplify.Algebraic, "((3 * x) - 2)"},
+		{"x - 2 * y + 3 * x + y", simplify.Algebraic, "((4 * x) - y)"},
+		{"-x + y", simplify.Algebraic, "(-x + y)"},
+		{"y - 3 * x", simplify.Algebraic, "(y - (3 * x))"},
+		{"x / 3 + x / 3", simplify.Algebraic, "((2 * x) / 3)"},
+		{"2 * x / 4", simplify.Algebraic, "(x / 2)"},
+		{"x * x * x", simplify.Algebraic, "(x ^ 3)"},
+		{"x ^ 2 * y / x", simplify.Algebraic, "(x * y)"},
+		{"x / (y * y)", simplify.Algebraic, "(x / (y ^ 2))"},
+		{"2 / x / x", simplify.Algebraic, "(2 / (x ^ 2))"},
+		{"-x * x", simplify.Algebraic, "(-1 * (x ^ 2))"},
+		{"(x + 1) * (x + 1) - (1 + x)", simplify.Algebraic, "((((x + 1) ^ 2) - x) - 1)"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := simplify.Simplify(n, c.mode).String(); s != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, s)
+			}
+		})
+	}
+}
+
+// TestSimplifyFloat checks that Float mode gives the same results as the
+// original expression, including for infinities and NaN.
+func TestSimplifyFloat(t *testing.T) {
+	expressions := []string{
+		"x * 1 + 0 - y * (2 - 1)",
+		"x ^ 0 + 1 ^ y",
+		"0 * x + x / 1",
+		"0 - x * -1",
+		"(x - x) * (y + 0) ^ 1",
+	}
+
+	values := []float64{0, 1, -1, 0.5, -3, math.Inf(1), math.Inf(-1), math.NaN()}
+
+	for i, s := range expressions {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := parser.ParseString(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			simplified := simplify.Simplify(n, simplify.Float)
+
+			for _, x := range values {
+				for _, y := range values {
+					constants := map[string]float64{"x": x, "y": y}
+
+					expected, err := evaluator.Evaluate(n, constants)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					actual, err := evaluator.Evaluate(simplified, constants)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if expected != actual && !(math.IsNaN(expected) && math.IsNaN(actual)) {
+						t.Errorf("x=%v, y=%v: %s gave %v but %s gave %v", x, y, n, expected, simplified, actual)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSimplifyEnv(t *testing.T) {
+	o := parser.DefaultOptions()
+	o.Binary["^"] = parser.Operator{Precedence: 1, Associativity: parser.LeftAssociative}
+
+	env := evaluator.NewEnv(nil)
+	env.Binary["^"] = func(a, b float64) float64 { return float64(int64(a) ^ int64(b)) }
+
+	cases := []struct {
+		s, expected string
+	}{
+		{"2 ^ 3", "1"},
+		{"x ^ 0", "(x ^ 0)"},
+		{"x * 1 + (5 ^ 3) * 2", "((x * 1) + 12)"},
+		{"1 ^ x", "(1 ^ x)"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			n, err := o.ParseString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := simplify.SimplifyEnv(n, env).String(); s != c.expected {
+				t.Fatalf("expected %s but got %s", c.expected, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Package snippet formats the part of a source which an error refers to, with
+// a marker underneath such as:
+//
+//	1 + )
+//	    ^
+package snippet
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The escape codes used to colour the marker.
+const (
+	colourStart = "\x1b[1;31m"
+	colourEnd   = "\x1b[0m"
+)
+
+// wide holds the ranges of runes which are displayed two cells wide, from the
+// wide and full width classes of Unicode's East Asian Width property.
+var wide = [][2]rune{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x231A, 0x231B},   // watch, hourglass
+	{0x2E80, 0x303E},   // CJK radicals and punctuation
+	{0x3041, 0x33FF},   // kana and CJK compatibility
+	{0x3400, 0x4DBF},   // CJK extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE30, 0xFE4F},   // CJK compatibility forms
+	{0xFF00, 0xFF60},   // full width forms
+	{0xFFE0, 0xFFE6},   // full width signs
+	{0x1F300, 0x1F64F}, // pictographs and emoticons
+	{0x1F900, 0x1F9FF}, // supplemental pictographs
+	{0x20000, 0x2FFFD}, // CJK extensions
+	{0x30000, 0x3FFFD}, // CJK extensions
+}
+
+// width returns the number of cells r takes up on a terminal.
+func width(r rune) int {
+	// Control characters and combining marks don't take up any space of their
+	// own.
+	if unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me) || r == '\u200b' {
+		return 0
+	}
+
+	for _, w := range wide {
+		if r >= w[0] && r <= w[1] {
+			return 2
+		}
+	}
+
+	return 1
+}
+
+// Position returns the line and column of the rune offset pos in src, both
+// starting from 1. Columns are counted in runes.
+func Position(src string, pos int) (int, int) {
+	line, column := 1, 1
+
+	for i, r := range []rune(src) {
+		if i == pos {
+			break
+		}
+
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+
+	return line, column
+}
+
+// Format returns the line of src containing the rune offset from, followed by
+// a line marking the span from from to to with "^~~~". An empty span, such as
+// the end of the source, is marked with a single "^". Spans which carry on
+// past the end of the line are cut short at the end of it.
+//
+// If colour is set then the marker is coloured with terminal escape codes.
+func Format(src string, from, to int, colour bool) string {
+	runes := []rune(src)
+
+	if from < 0 {
+		from = 0
+	} else if from > len(runes) {
+		from = len(runes)
+	}
+
+	// Find the line which the span starts on.
+	start := from
+	for start > 0 && runes[start-1] != '\n' {
+		start--
+	}
+
+	end := from
+	for end < len(runes) && runes[end] != '\n' {
+		end++
+	}
+
+	if to > end {
+		to = end
+	}
+
+	line := runes[start:end]
+
+	// Pad up to the span, keeping tabs so that it lines up however wide they
+	// are shown.
+	pad := strings.Builder{}
+	for _, r := range line[:from-start] {
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteString(strings.Repeat(" ", width(r)))
+		}
+	}
+
+	// Underline each cell of the span, starting with a caret.
+	marker := strings.Builder{}
+	marker.WriteString("^")
+
+	cells := 0
+	for _, r := range runes[from:maxInt(from, to)] {
+		if r == '\t' {
+			cells++
+		} else {
+			cells += width(r)
+		}
+	}
+
+	if cells > 1 {
+		marker.WriteString(strings.Repeat("~", cells-1))
+	}
+
+	if colour {
+		return string(line) + "\n" + pad.String() + colourStart + marker.String() + colourEnd + "\n"
+	}
+
+	return string(line) + "\n" + pad.String() + marker.String() + "\n"
+}
+
+// maxInt returns the larger of a and b.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
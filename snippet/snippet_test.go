@@ -0,0 +1,69 @@
+package snippet_test
+
+import (
+	"github.com/jackwilsdon/go-calc/snippet"
+	"strconv"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		src      string
+		from, to int
+		expected string
+	}{
+		{"1 + )", 4, 5, "1 + )\n    ^\n"},
+		{"1 + foo * 2", 4, 7, "1 + foo * 2\n    ^~~\n"},
+		{"1 +", 3, 3, "1 +\n   ^\n"},
+		{"π + x", 4, 5, "π + x\n    ^\n"},
+		{"2 * π", 4, 5, "2 * π\n    ^\n"},
+		{"円 + x", 4, 5, "円 + x\n     ^\n"},
+		{"1 + 円円", 4, 6, "1 + 円円\n    ^~~~\n"},
+		{"é + x", 5, 6, "é + x\n    ^\n"},
+		{"\t1 + )", 5, 6, "\t1 + )\n\t    ^\n"},
+		{"1 +\n2 * )\n3", 8, 9, "2 * )\n    ^\n"},
+		{"1 +\n(2 *\n3", 4, 11, "(2 *\n^~~~\n"},
+		{"1 +\n", 4, 4, "\n^\n"},
+		{"", 0, 0, "\n^\n"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			if actual := snippet.Format(c.src, c.from, c.to, false); actual != c.expected {
+				t.Fatalf("expected %q but got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormatColour(t *testing.T) {
+	expected := "1 + )\n    \x1b[1;31m^\x1b[0m\n"
+
+	if actual := snippet.Format("1 + )", 4, 5, true); actual != expected {
+		t.Fatalf("expected %q but got %q", expected, actual)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	cases := []struct {
+		src          string
+		pos          int
+		line, column int
+	}{
+		{"1 + 2", 0, 1, 1},
+		{"1 + 2", 4, 1, 5},
+		{"1 +\n2 * )", 8, 2, 5},
+		{"π +\nπ", 4, 2, 1},
+		{"1 +", 3, 1, 4},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			line, column := snippet.Position(c.src, c.pos)
+
+			if line != c.line || column != c.column {
+				t.Fatalf("expected %d:%d but got %d:%d", c.line, c.column, line, column)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+// Package solve finds the real roots of expressions and equations
+// numerically.
+package solve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackwilsdon/go-calc/ast"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"math"
+	"sort"
+)
+
+// Options controls how roots are searched for.
+type Options struct {
+	// Min and Max bound the interval which is scanned for sign changes.
+	Min, Max float64
+
+	// Samples is the number of pieces the interval is split into while
+	// scanning for sign changes.
+	Samples int
+
+	// Guess is the starting point for Newton's method when the scan doesn't
+	// find any sign changes.
+	Guess float64
+
+	// Tolerance is how close an estimate must be to a root, relative to the
+	// larger of 1 and the magnitude of the root.
+	Tolerance float64
+
+	// MaxIterations is the number of iterations which may be spent refining
+	// each root.
+	MaxIterations int
+}
+
+// DefaultOptions returns a reasonable set of options to pass to Solve and
+// Roots, which scans from -1000 to 1000.
+func DefaultOptions() Options {
+	return Options{
+		Min:           -1000,
+		Max:           1000,
+		Samples:       10000,
+		Guess:         1,
+		Tolerance:     1e-12,
+		MaxIterations: 100,
+	}
+}
+
+// validate returns an error if the interval in o can't be scanned, or its
+// roots can't be refined.
+func (o Options) validate() error {
+	if o.Samples <= 0 {
+		return fmt.Errorf("invalid number of samples %d, must be at least 1", o.Samples)
+	}
+
+	if o.MaxIterations <= 0 {
+		return fmt.Errorf("invalid number of iterations %d, must be at least 1", o.MaxIterations)
+	}
+
+	// This is written so that NaN is rejected too.
+	if !(o.Tolerance >= 0) {
+		return fmt.Errorf("invalid tolerance %v, must not be negative", o.Tolerance)
+	}
+
+	// Infinite bounds would make every step infinite or NaN.
+	if math.IsInf(o.Min, 0) || math.IsInf(o.Max, 0) {
+		return fmt.Errorf("invalid interval from %v to %v, the bounds must be finite", o.Min, o.Max)
+	}
+
+	// This is written so that NaN bounds are rejected too.
+	if !(o.Max > o.Min) {
+		return fmt.Errorf("invalid interval from %v to %v, the maximum must be greater than the minimum", o.Min, o.Max)
+	}
+
+	return nil
+}
+
+// ConvergenceError is returned when a method fails to converge on a root
+// within the iteration limit.
+type ConvergenceError struct {
+	// Method is the name of the method which failed.
+	Method string
+
+	// Iterations is the number of iterations which were performed.
+	Iterations int
+
+	// X is the last estimate of the root, and FX the value at it.
+	X, FX float64
+}
+
+func (e *ConvergenceError) Error() string {
+	return fmt.Sprintf("%s did not converge after %d iterations (f(%v) = %v)", e.Method, e.Iterations, e.X, e.FX)
+}
+
+// function evaluates an expression, along with its derivative, for a single
+// variable.
+type function struct {
+	ctx context.Context
+	n   ast.Node
+	x   string
+	env evaluator.Env
+}
+
+func newFunction(ctx context.Context, n ast.Node, x string, constants map[string]float64) function {
+	// Copy the constants so that we can set the variable without affecting
+	// the caller.
+	c := make(map[string]float64, len(constants)+1)
+
+	for k, v := range constants {
+		c[k] = v
+	}
+
+	return function{ctx: ctx, n: n, x: x, env: evaluator.NewEnv(c)}
+}
+
+// eval returns the value and derivative of f at x.
+func (f function) eval(x float64) (float64, float64, error) {
+	f.env.Constants[f.x] = x
+
+	v, d, err := evaluator.GradientContext(f.ctx, f.n, f.env, []string{f.x})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return v, d[0], nil
+}
+
+// close returns whether a and b are within the tolerance of each other.
+func (o Options) close(a, b float64) bool {
+	return math.Abs(a-b) <= o.Tolerance*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+// finite returns whether v is neither infinite nor NaN.
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// bracket refines the root of f between a and b, where f(a) and f(b) have
+// different signs. Newton's method is used while its steps stay inside the
+// bracket, falling back to bisection when they don't.
+func bracket(f function, a, b, fa float64, o Options) (float64, error) {
+	x := a + (b-a)/2
+
+	for i := 0; i < o.MaxIterations; i++ {
+		fx, dx, err := f.eval(x)
+		if err != nil {
+			return 0, err
+		}
+
+		if fx == 0 {
+			return x, nil
+		}
+
+		// Shrink the bracket so that it still holds the sign change.
+		if (fx < 0) == (fa < 0) {
+			a, fa = x, fx
+		} else {
+			b = x
+		}
+
+		if o.close(a, b) {
+			return a + (b-a)/2, nil
+		}
+
+		next := x - fx/dx
+
+		if !finite(next) || next <= math.Min(a, b) || next >= math.Max(a, b) {
+			next = a + (b-a)/2
+		}
+
+		if o.close(next, x) {
+			return next, nil
+		}
+
+		x = next
+	}
+
+	fx, _, err := f.eval(x)
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, &ConvergenceError{Method: "bisection", Iterations: o.MaxIterations, X: x, FX: fx}
+}
+
+// newton finds a root of f starting from guess. Newton's method is used while
+// the derivative is usable, and the secant method otherwise.
+func newton(f function, guess float64, o Options) (float64, error) {
+	x := guess
+	method := "newton"
+
+	// The previous estimate is kept for the secant method.
+	prev := x + math.Max(1, math.Abs(x))*1e-4
+	fprev, _, err := f.eval(prev)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < o.MaxIterations; i++ {
+		fx, dx, err := f.eval(x)
+		if err != nil {
+			return 0, err
+		}
+
+		if fx == 0 {
+			return x, nil
+		}
+
+		next := x - fx/dx
+		method = "newton"
+
+		if dx == 0 || !finite(next) {
+			next = x - fx*(x-prev)/(fx-fprev)
+			method = "secant"
+		}
+
+		if !finite(next) {
+			return 0, &ConvergenceError{Method: method, Iterations: i + 1, X: x, FX: fx}
+		}
+
+		if o.close(next, x) {
+			return next, nil
+		}
+
+		prev, fprev, x = x, fx, next
+	}
+
+	fx, _, err := f.eval(x)
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, &ConvergenceError{Method: method, Iterations: o.MaxIterations, X: x, FX: fx}
+}
+
+// touching returns whether f touches zero around b, where fb is closer to
+// zero than fa and fc on either side of it without changing sign.
+func touching(fa, fb, fc float64) bool {
+	if !finite(fa) || !finite(fb) || !finite(fc) || fb == 0 {
+		return false
+	}
+
+	if (fa < 0) != (fb < 0) || (fb < 0) != (fc < 0) {
+		return false
+	}
+
+	return math.Abs(fb) < math.Abs(fa) && math.Abs(fb) <= math.Abs(fc)
+}
+
+// touch looks for a root of f between a and c where f touches zero without
+// changing sign, such as in x^2, starting from b. Newton's method still
+// finds these roots, although more slowly than others.
+func touch(f function, a, b, c float64, o Options) (float64, bool, error) {
+	r, err := newton(f, b, o)
+
+	// Most minima don't reach zero, so Newton's method won't converge.
+	var convergenceErr *ConvergenceError
+	if errors.As(err, &convergenceErr) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	if r < a || r > c {
+		return 0, false, nil
+	}
+
+	fr, _, err := f.eval(r)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return r, math.Abs(fr) <= o.Tolerance, nil
+}
+
+// Roots returns the real roots of n in the variable x, in ascending order.
+// Any other constants are looked up in constants.
+//
+// The interval between o.Min and o.Max is scanned for sign changes, each of
+// which is refined into a root. Roots where the expression touches zero
+// without changing sign, such as in x^2, are found from the samples closest
+// to zero around them. If there aren't any roots then Newton's method is
+// started from o.Guess, finding at most one root.
+//
+// Each evaluation of n is limited to evaluator.DefaultMaxOperations, but there
+// are o.Samples of them and more while refining, so use RootsContext to bound
+// the total time.
+func Roots(n ast.Node, x string, constants map[string]float64, o Options) ([]float64, error) {
+	return RootsContext(context.Background(), n, x, constants, o)
+}
+
+// RootsContext returns the real roots of n like Roots, stopping with an
+// evaluator.LimitError if ctx is done before they are found.
+func RootsContext(ctx context.Context, n ast.Node, x string, constants map[string]float64, o Options) ([]float64, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	f := newFunction(ctx, n, x, constants)
+
+	var roots []float64
+
+	add := func(r float64) {
+		for _, existing := range roots {
+			if o.close(existing, r) {
+				return
+			}
+		}
+
+		roots = append(roots, r)
+	}
+
+	step := (o.Max - o.Min) / float64(o.Samples)
+	a := o.Min
+
+	// The sample before a is kept to spot where f touches zero.
+	prev, fprev := math.NaN(), math.NaN()
+
+	fa, _, err := f.eval(a)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i <= o.Samples; i++ {
+		b := o.Min + step*float64(i)
+
+		fb, _, err := f.eval(b)
+		if err != nil {
+			return nil, err
+		}
+
+		if fa == 0 {
+			add(a)
+		} else if finite(fa) && finite(fb) && fb != 0 && (fa < 0) != (fb < 0) {
+			r, err := bracket(f, a, b, fa, o)
+			if err != nil {
+				return nil, err
+			}
+
+			// Sign changes across poles, such as in 1/x, don't have a root.
+			if fr, _, err := f.eval(r); err != nil {
+				return nil, err
+			} else if math.Abs(fr) <= math.Min(math.Abs(fa), math.Abs(fb)) {
+				add(r)
+			}
+		} else if touching(fprev, fa, fb) {
+			r, ok, err := touch(f, prev, a, b, o)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				add(r)
+			}
+		}
+
+		prev, fprev = a, fa
+		a, fa = b, fb
+	}
+
+	if fa == 0 {
+		add(a)
+	}
+
+	if len(roots) == 0 {
+		r, err := newton(f, o.Guess, o)
+		if err != nil {
+			return nil, err
+		}
+
+		add(r)
+	}
+
+	sort.Float64s(roots)
+
+	return roots, nil
+}
+
+// Solve returns the real values of x for which lhs equals rhs, by finding the
+// roots of lhs - rhs.
+func Solve(lhs, rhs ast.Node, x string, constants map[string]float64, o Options) ([]float64, error) {
+	return SolveContext(context.Background(), lhs, rhs, x, constants, o)
+}
+
+// SolveContext returns the real values of x for which lhs equals rhs like
+// Solve, stopping with an evaluator.LimitError if ctx is done before they are
+// found.
+func SolveContext(ctx context.Context, lhs, rhs ast.Node, x string, constants map[string]float64, o Options) ([]float64, error) {
+	return RootsContext(ctx, ast.BinaryExpr{Left: lhs, Right: rhs, Op: "-", OpPos: lhs.End()}, x, constants, o)
+}
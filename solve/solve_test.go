@@ -0,0 +1,184 @@
+package solve_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackwilsdon/go-calc/evaluator"
+	"github.com/jackwilsdon/go-calc/parser"
+	"github.com/jackwilsdon/go-calc/solve"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSolve(t *testing.T) {
+	cases := []struct {
+		s     string
+		x     string
+		roots []float64
+	}{
+		{"x = 2", "x", []float64{2}},
+		{"2 * x + 1 = 0", "x", []float64{-0.5}},
+		{"x ^ 2 = 2", "x", []float64{-math.Sqrt2, math.Sqrt2}},
+		{"x ^ 2 = 0", "x", []float64{0}},
+		// These roots touch zero between samples without changing sign.
+		{"(x - 0.3) ^ 2 = 0", "x", []float64{0.3}},
+		{"(x - 0.3) ^ 2 * (x - 5) = 0", "x", []float64{0.3, 5}},
+		{"(x + 1.01) ^ 2 * (x - 2.07) ^ 2 = 0", "x", []float64{-1.01, 2.07}},
+		{"0 - (x - 7.77) ^ 4 = 0", "x", []float64{7.77}},
+		{"1000 * (1 + r) ^ 10 = 2000", "r", []float64{-1 - math.Pow(2, 0.1), math.Pow(2, 0.1) - 1}},
+		{"(x - 1) * (x - 2) * (x - 3) = 0", "x", []float64{1, 2, 3}},
+		{"1 / x = 0.5", "x", []float64{2}},
+		{"x ^ 3 = Pi", "x", []float64{math.Cbrt(math.Pi)}},
+		{"2 ^ x = 5000", "x", []float64{math.Log2(5000)}},
+		// This root lies outside of the scanned interval, so Newton's method
+		// is used to find it.
+		{"x = 123456", "x", []float64{123456}},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			lhs, rhs, err := parser.ParseEquationString(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			roots, err := solve.Solve(lhs, rhs, c.x, map[string]float64{"Pi": math.Pi}, solve.DefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(roots) != len(c.roots) {
+				t.Fatalf("expected roots %v but got %v", c.roots, roots)
+			}
+
+			for j, r := range roots {
+				if math.Abs(r-c.roots[j]) > 1e-9*math.Max(1, math.Abs(r)) {
+					t.Errorf("expected roots %v but got %v", c.roots, roots)
+				}
+			}
+		})
+	}
+}
+
+func TestSolveNoConvergence(t *testing.T) {
+	lhs, rhs, err := parser.ParseEquationString("x ^ 2 + 1 = 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = solve.Solve(lhs, rhs, "x", nil, solve.DefaultOptions())
+
+	var convergenceErr *solve.ConvergenceError
+	if !errors.As(err, &convergenceErr) {
+		t.Fatalf("expected a convergence error but got %v", err)
+	}
+
+	if convergenceErr.Iterations != solve.DefaultOptions().MaxIterations {
+		t.Errorf("expected %d iterations but got %d", solve.DefaultOptions().MaxIterations, convergenceErr.Iterations)
+	}
+}
+
+func TestSolveInvalidOptions(t *testing.T) {
+	lhs, rhs, err := parser.ParseEquationString("x = 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noSamples := solve.DefaultOptions()
+	noSamples.Samples = 0
+
+	backwards := solve.DefaultOptions()
+	backwards.Min, backwards.Max = 10, -10
+
+	empty := solve.DefaultOptions()
+	empty.Min, empty.Max = 1, 1
+
+	noIterations := solve.DefaultOptions()
+	noIterations.MaxIterations = 0
+
+	negativeIterations := solve.DefaultOptions()
+	negativeIterations.MaxIterations = -5
+
+	negativeTolerance := solve.DefaultOptions()
+	negativeTolerance.Tolerance = -1e-12
+
+	nanTolerance := solve.DefaultOptions()
+	nanTolerance.Tolerance = math.NaN()
+
+	infiniteMin := solve.DefaultOptions()
+	infiniteMin.Min = math.Inf(-1)
+
+	infiniteMax := solve.DefaultOptions()
+	infiniteMax.Max = math.Inf(1)
+
+	cases := []struct {
+		o   solve.Options
+		err string
+	}{
+		{noSamples, "invalid number of samples 0, must be at least 1"},
+		{noIterations, "invalid number of iterations 0, must be at least 1"},
+		{negativeIterations, "invalid number of iterations -5, must be at least 1"},
+		{negativeTolerance, "invalid tolerance -1e-12, must not be negative"},
+		{nanTolerance, "invalid tolerance NaN, must not be negative"},
+		{infiniteMin, "invalid interval from -Inf to 1000, the bounds must be finite"},
+		{infiniteMax, "invalid interval from -1000 to +Inf, the bounds must be finite"},
+		{backwards, "invalid interval from 10 to -10, the maximum must be greater than the minimum"},
+		{empty, "invalid interval from 1 to 1, the maximum must be greater than the minimum"},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			_, err := solve.Solve(lhs, rhs, "x", nil, c.o)
+			if err == nil || err.Error() != c.err {
+				t.Fatalf("expected error %q but got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestSolveUnknownConstant(t *testing.T) {
+	lhs, rhs, err := parser.ParseEquationString("x = y")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := solve.Solve(lhs, rhs, "x", nil, solve.DefaultOptions()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSolveContext(t *testing.T) {
+	lhs, rhs, err := parser.ParseEquationString("x ^ 2 = 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = solve.SolveContext(ctx, lhs, rhs, "x", nil, solve.DefaultOptions())
+	if !errors.Is(err, evaluator.ContextLimit) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a context limit error but got %v", err)
+	}
+
+	// Each sample is cheap, but together they would take far longer than the
+	// deadline.
+	o := solve.DefaultOptions()
+	o.Samples = 1 << 30
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err = solve.SolveContext(ctx, lhs, rhs, "x", nil, o)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be exceeded but got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected solving to stop quickly but it took %s", elapsed)
+	}
+}
@@ -3,9 +3,21 @@ package token
 import (
 	"bufio"
 	"io"
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
+// defaultOperators holds the operators recognised by scanners created with
+// NewScanner.
+var defaultOperators = []string{"+", "-", "*", "/", "^", "=", "!", "!!", "%"}
+
+// DefaultOperators returns the operators recognised by scanners created with
+// NewScanner. The slice is new on every call, so it can be changed freely.
+func DefaultOperators() []string {
+	return append([]string(nil), defaultOperators...)
+}
+
 func isWhitespace(r rune) bool {
 	return r == ' ' || r == '\t' || r == '\n'
 }
@@ -14,22 +26,21 @@ func isDigit(r rune) bool {
 	return (r >= '0' && r <= '9') || r == '.'
 }
 
-func isOperator(r rune) bool {
-	return r == '+' || r == '-' || r == '*' || r == '/' || r == '^'
-}
-
 func isParenthesis(r rune) bool {
 	return r == '(' || r == ')'
 }
 
 func isConstant(r rune) bool {
-	return !isWhitespace(r) && !isDigit(r) && !isOperator(r) && !isParenthesis(r)
+	return !isWhitespace(r) && !isDigit(r) && !isParenthesis(r)
 }
 
 // Scanner converts a stream of runes into a stream of tokens.
 type Scanner struct {
 	r        *bufio.Reader
 	position int
+
+	// operators holds the operators to recognise, longest first.
+	operators []string
 }
 
 // read reads and returns the next rune.
@@ -103,6 +114,60 @@ func (s *Scanner) scanWhile(check func(r rune) bool) (string, error) {
 	}
 }
 
+// operator returns the longest operator at the current position without
+// reading it, or an empty string if there isn't one.
+func (s *Scanner) operator() (string, error) {
+	for _, op := range s.operators {
+		b, err := s.r.Peek(len(op))
+
+		if err == nil && string(b) == op {
+			return op, nil
+		}
+
+		// Running out of input just means that this operator can't match.
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return "", err
+		}
+	}
+
+	return "", nil
+}
+
+// scanConstant reads runes until one which can't be part of a constant, or the
+// start of an operator.
+func (s *Scanner) scanConstant() (string, error) {
+	b := strings.Builder{}
+
+	for {
+		// Operators take priority over constants.
+		op, err := s.operator()
+
+		if err != nil {
+			return "", err
+		}
+
+		if op != "" {
+			return b.String(), nil
+		}
+
+		r, ok, err := s.scan(isConstant)
+
+		if err == io.EOF {
+			return b.String(), nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if !ok {
+			return b.String(), nil
+		}
+
+		b.WriteRune(r)
+	}
+}
+
 // Scan reads and returns the next token.
 func (s *Scanner) Scan() (Token, error) {
 	var startPosition int
@@ -130,16 +195,22 @@ func (s *Scanner) Scan() (Token, error) {
 	// Update the start position before we scan for an operator.
 	startPosition = s.position
 
-	// Just try and eat one operator.
-	op, isOp, err := s.scan(isOperator)
+	// Just try and eat one operator, preferring the longest.
+	op, err := s.operator()
 
 	if err != nil {
 		return Token{}, err
 	}
 
 	// If it did match then it's an operator.
-	if isOp {
-		return Token{OperatorToken, string(op), startPosition}, nil
+	if op != "" {
+		if _, err := s.r.Discard(len(op)); err != nil {
+			return Token{}, err
+		}
+
+		s.position += utf8.RuneCountInString(op)
+
+		return Token{OperatorToken, op, startPosition}, nil
 	}
 
 	// Update the start position before we scan for parentheses.
@@ -158,12 +229,17 @@ func (s *Scanner) Scan() (Token, error) {
 	}
 
 	// The only thing this can now be is a constant.
-	constant, err := s.scanWhile(isConstant)
+	constant, err := s.scanConstant()
 
 	if err != nil {
 		return Token{}, err
 	}
 
+	// Nothing matched, which can only happen at the end of the input.
+	if constant == "" {
+		return Token{}, io.EOF
+	}
+
 	return Token{ConstantToken, constant, startPosition}, nil
 }
 
@@ -189,5 +265,26 @@ func (s *Scanner) ScanAll() ([]Token, error) {
 
 // NewScanner creates a new scanner which reads from r.
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{r: bufio.NewReader(r), position: 0}
+	return NewOperatorScanner(r, defaultOperators)
+}
+
+// NewOperatorScanner creates a new scanner which reads from r and recognises
+// ops as operators, instead of DefaultOperators(). Where operators overlap the
+// longest one is used, so "**" is scanned as a single operator even if "*" is
+// also an operator. Operators can't contain whitespace, digits or
+// parentheses, and constants are split wherever an operator starts.
+func NewOperatorScanner(r io.Reader, ops []string) *Scanner {
+	operators := make([]string, 0, len(ops))
+
+	for _, op := range ops {
+		if op != "" {
+			operators = append(operators, op)
+		}
+	}
+
+	sort.SliceStable(operators, func(i, j int) bool {
+		return len(operators[i]) > len(operators[j])
+	})
+
+	return &Scanner{r: bufio.NewReader(r), position: 0, operators: operators}
 }
@@ -2,6 +2,7 @@ package token_test
 
 import (
 	"github.com/jackwilsdon/go-calc/token"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -149,3 +150,81 @@ func TestScannerScanAll(t *testing.T) {
 		})
 	}
 }
+
+func TestOperatorScanner(t *testing.T) {
+	ops := []string{"*", "**", "^", "xor", "→"}
+
+	cases := []struct {
+		s string
+		t []token.Token
+	}{
+		{
+			"2**3*4",
+			[]token.Token{
+				{token.NumberToken, "2", 0},
+				{token.OperatorToken, "**", 1},
+				{token.NumberToken, "3", 3},
+				{token.OperatorToken, "*", 4},
+				{token.NumberToken, "4", 5},
+			},
+		},
+		{
+			"axorb ^ π→x",
+			[]token.Token{
+				{token.ConstantToken, "a", 0},
+				{token.OperatorToken, "xor", 1},
+				{token.ConstantToken, "b", 4},
+				{token.OperatorToken, "^", 6},
+				{token.ConstantToken, "π", 8},
+				{token.OperatorToken, "→", 9},
+				{token.ConstantToken, "x", 10},
+			},
+		},
+		{
+			// Default operators which haven't been asked for are constants.
+			"a+b / c",
+			[]token.Token{
+				{token.ConstantToken, "a+b", 0},
+				{token.ConstantToken, "/", 4},
+				{token.ConstantToken, "c", 6},
+			},
+		},
+	}
+
+	for i, c := range cases {
+		t.Run("case "+strconv.Itoa(i), func(t *testing.T) {
+			s, err := token.NewOperatorScanner(strings.NewReader(c.s), ops).ScanAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(s, c.t) {
+				t.Fatalf("expected %v but got %v", c.t, s)
+			}
+		})
+	}
+}
+
+func TestDefaultOperatorsCopy(t *testing.T) {
+	ops := token.DefaultOperators()
+	ops[0] = "x"
+
+	if token.DefaultOperators()[0] != "+" {
+		t.Fatal("expected changes to the returned slice to not affect the defaults")
+	}
+
+	ts, err := token.NewScanner(strings.NewReader("x+1")).ScanAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []token.Token{
+		{token.ConstantToken, "x", 0},
+		{token.OperatorToken, "+", 1},
+		{token.NumberToken, "1", 2},
+	}
+
+	if !reflect.DeepEqual(ts, expected) {
+		t.Fatalf("expected %v but got %v", expected, ts)
+	}
+}